@@ -0,0 +1,190 @@
+package nifireceiver
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+)
+
+const (
+	bulletinCursorKey = "bulletin_cursor"
+
+	// bulletinTimestampFormat is the timestamp layout expected by the translator
+	bulletinTimestampFormat = "2006-01-02T15:04:05.000Z"
+)
+
+// bulletinNamespace is used to derive stable object uuids from node addresses and bulletin ids
+var bulletinNamespace = uuid.MustParse("0d0b6f0e-2f7c-4a36-8c3a-9a51d6b7e4c2")
+
+// bulletinPoller polls the NiFi bulletin board for new bulletins
+type bulletinPoller struct {
+	logger     *zap.Logger
+	config     APIConfig
+	client     *nifiapi.Client
+	storage    storage.Client
+	consume    func(context.Context, []translator.BulletinEvent) error
+	groupNames *groupNameCache
+
+	// cursor is the id of the last consumed bulletin, -1 when nothing was consumed yet
+	cursor int64
+
+	// location resolves the zone abbreviation of the bulletin times
+	location *time.Location
+}
+
+func newBulletinPoller(
+	logger *zap.Logger,
+	config APIConfig,
+	client *nifiapi.Client,
+	groupNames *groupNameCache,
+	storageClient storage.Client,
+	consume func(context.Context, []translator.BulletinEvent) error,
+) *bulletinPoller {
+	return &bulletinPoller{
+		logger:     logger,
+		config:     config,
+		client:     client,
+		storage:    storageClient,
+		consume:    consume,
+		groupNames: groupNames,
+		cursor:     -1,
+		location:   config.location(),
+	}
+}
+
+// loadCursor restores the cursor from storage
+func (p *bulletinPoller) loadCursor(ctx context.Context) error {
+	data, err := p.storage.Get(ctx, bulletinCursorKey)
+	if err != nil {
+		return fmt.Errorf("failed to load bulletin cursor: %w", err)
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	cursor, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		p.logger.Warn("ignoring invalid bulletin cursor", zap.Error(err))
+		return nil
+	}
+
+	p.cursor = cursor
+	return nil
+}
+
+// run polls the bulletin board every collection interval until ctx is done
+func (p *bulletinPoller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.CollectionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.poll(ctx); err != nil {
+				p.logger.Error("Failed to poll bulletins", zap.Error(err))
+			}
+		}
+	}
+}
+
+// poll fetches and consumes the bulletins newer than the cursor, until the board is drained
+func (p *bulletinPoller) poll(ctx context.Context) error {
+	for {
+		board, err := p.client.GetBulletinBoard(ctx, p.cursor, p.config.Bulletins.Limit)
+		if err != nil {
+			return fmt.Errorf("failed to get bulletin board: %w", err)
+		}
+
+		now := time.Now()
+		cursor := p.cursor
+		events := make([]translator.BulletinEvent, 0, len(board.Bulletins))
+		for _, entity := range board.Bulletins {
+			cursor = max(cursor, entity.ID)
+			if entity.Bulletin == nil || !entity.CanRead {
+				continue
+			}
+			events = append(events, p.toBulletinEvent(ctx, entity, now))
+		}
+
+		if len(events) > 0 {
+			if err := p.consume(ctx, events); err != nil {
+				return fmt.Errorf("failed to consume bulletins: %w", err)
+			}
+		}
+
+		if cursor == p.cursor {
+			return nil
+		}
+
+		p.cursor = cursor
+		if err := p.storage.Set(ctx, bulletinCursorKey, []byte(strconv.FormatInt(cursor, 10))); err != nil {
+			return fmt.Errorf("failed to persist bulletin cursor: %w", err)
+		}
+
+		if len(board.Bulletins) < p.config.Bulletins.Limit {
+			return nil
+		}
+	}
+}
+
+// toBulletinEvent maps a bulletin board entry into the reporting task's bulletin format
+func (p *bulletinPoller) toBulletinEvent(ctx context.Context, entity nifiapi.BulletinEntity, now time.Time) translator.BulletinEvent {
+	bulletin := entity.Bulletin
+	objectID := uuid.NewSHA1(bulletinNamespace, []byte(fmt.Sprintf("%s/%d", bulletin.NodeAddress, bulletin.ID)))
+
+	return translator.BulletinEvent{
+		ObjectId:             objectID.String(),
+		Platform:             "nifi",
+		BulletinId:           bulletin.ID,
+		BulletinCategory:     bulletin.Category,
+		BulletinGroupId:      bulletin.GroupID,
+		BulletinGroupName:    p.groupNames.get(ctx, bulletin.GroupID),
+		BulletinLevel:        bulletin.Level,
+		BulletinMessage:      bulletin.Message,
+		BulletinNodeAddress:  bulletin.NodeAddress,
+		BulletinSourceId:     bulletin.SourceID,
+		BulletinSourceName:   bulletin.SourceName,
+		BulletinSourceType:   bulletin.SourceType,
+		BulletinTimestamp:    parseBulletinTime(bulletin.Timestamp, now, p.location).UTC().Format(bulletinTimestampFormat),
+		BulletinFlowFileUuid: bulletin.FlowFileUUID,
+	}
+}
+
+// parseBulletinTime resolves a bulletin's time of day into the most recent matching
+// point in time, falling back to now when it can't be parsed. The time of day is taken in loc,
+// or UTC, so the day and offset are the ones of the NiFi instance on that date
+func parseBulletinTime(value string, now time.Time, loc *time.Location) time.Time {
+	if _, err := time.Parse(nifiapi.BulletinTimeFormat, value); err != nil {
+		return now
+	}
+
+	// the abbreviation is only resolved against a date, parsing it along with a time of day
+	// uses the offset loc had in year 0
+	clock, abbreviation, _ := strings.Cut(value, " ")
+	tod, err := time.Parse(time.TimeOnly, clock)
+	if err != nil {
+		return now
+	}
+	if abbreviation == "UTC" || abbreviation == "GMT" {
+		loc = time.UTC
+	}
+
+	ref := now.In(loc)
+	ts := time.Date(ref.Year(), ref.Month(), ref.Day(), tod.Hour(), tod.Minute(), tod.Second(), 0, loc)
+	if ts.After(now.Add(time.Minute)) {
+		ts = ts.AddDate(0, 0, -1)
+	}
+	return ts
+}
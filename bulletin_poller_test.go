@@ -0,0 +1,100 @@
+package nifireceiver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+)
+
+func TestBulletinPoller(t *testing.T) {
+	var bulletins []nifiapi.BulletinEntity
+	for i := int64(1); i <= 5; i++ {
+		bulletins = append(bulletins, nifiapi.BulletinEntity{
+			ID:      i,
+			CanRead: true,
+			Bulletin: &nifiapi.BulletinDTO{
+				ID:          i,
+				NodeAddress: "nifi-0:8443",
+				GroupID:     "group",
+				SourceName:  "InvokeHTTP",
+				Level:       "ERROR",
+				Message:     "failed",
+				Timestamp:   time.Now().UTC().Format(nifiapi.BulletinTimeFormat),
+			},
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/nifi-api/flow/bulletin-board":
+			after, err := strconv.ParseInt(req.URL.Query().Get("after"), 10, 64)
+			if err != nil {
+				after = -1
+			}
+			limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+
+			var results []nifiapi.BulletinEntity
+			for _, bulletin := range bulletins {
+				if bulletin.ID > after && len(results) < limit {
+					results = append(results, bulletin)
+				}
+			}
+			_ = json.NewEncoder(w).Encode(nifiapi.BulletinBoardEntity{BulletinBoard: nifiapi.BulletinBoardDTO{Bulletins: results}})
+		default:
+			_ = json.NewEncoder(w).Encode(nifiapi.ProcessGroupEntity{Component: nifiapi.ProcessGroupDTO{Name: "ingest"}})
+		}
+	}))
+	defer server.Close()
+
+	var consumed []translator.BulletinEvent
+	consume := func(_ context.Context, events []translator.BulletinEvent) error {
+		consumed = append(consumed, events...)
+		return nil
+	}
+
+	cfg := createDefaultConfig().(*Config).API
+	cfg.Bulletins.Limit = 2
+
+	client := nifiapi.NewClient(server.Client(), server.URL)
+	poller := newBulletinPoller(zap.NewNop(), cfg, client, newGroupNameCache(zap.NewNop(), client), storage.NewNopClient(), consume)
+	require.NoError(t, poller.loadCursor(context.Background()))
+
+	require.NoError(t, poller.poll(context.Background()))
+	require.Len(t, consumed, 5)
+	assert.Equal(t, int64(5), poller.cursor)
+	assert.Equal(t, "ingest", consumed[0].BulletinGroupName)
+	assert.NotEqual(t, consumed[0].ObjectId, consumed[1].ObjectId)
+
+	_, err := time.Parse(bulletinTimestampFormat, consumed[0].BulletinTimestamp)
+	assert.NoError(t, err)
+
+	consumed = nil
+	require.NoError(t, poller.poll(context.Background()))
+	assert.Empty(t, consumed)
+}
+
+func TestParseBulletinTime(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 30, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 2, 29, 23, 59, 50, 0, time.UTC), parseBulletinTime("23:59:50 UTC", now, time.UTC).UTC())
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 10, 0, time.UTC), parseBulletinTime("00:00:10 UTC", now, time.UTC).UTC())
+	assert.Equal(t, now, parseBulletinTime("invalid", now, time.UTC))
+
+	// just past midnight in Berlin, while it is still the previous evening in UTC
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	now = time.Date(2024, 7, 1, 22, 0, 30, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 7, 1, 21, 59, 50, 0, time.UTC), parseBulletinTime("23:59:50 CEST", now, berlin).UTC())
+	assert.Equal(t, time.Date(2024, 7, 1, 22, 0, 10, 0, time.UTC), parseBulletinTime("00:00:10 CEST", now, berlin).UTC())
+}
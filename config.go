@@ -1,8 +1,15 @@
 package nifireceiver
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/config/configopaque"
 )
 
 type Config struct {
@@ -12,4 +19,380 @@ type Config struct {
 	ContextPropagationAliases map[string]string                `mapstructure:"context_propagation_aliases,omitempty"`
 	BulletinURLPath           string                           `mapstructure:"bulletin_url_path,omitempty"`
 	ProvenanceURLPath         string                           `mapstructure:"provenance_url_path,omitempty"`
+
+	// StreamingFlushSize enables decoding pushed provenance batches incrementally, events are
+	// translated and flushed to the pipelines every StreamingFlushSize events, 0 disables streaming
+	StreamingFlushSize int `mapstructure:"streaming_flush_size,omitempty"`
+
+	// Queue configures asynchronous ingestion, pushed events are acknowledged once queued
+	Queue QueueConfig `mapstructure:"queue"`
+
+	// Traces configures the shape of the traces built from provenance events
+	Traces TracesConfig `mapstructure:"traces"`
+
+	// Lineage configures which trace the flowfiles created by FORK, CLONE and JOIN events belong to
+	Lineage LineageConfig `mapstructure:"lineage"`
+
+	// Resource configures the resource, and service.name, events are grouped under
+	Resource ResourceConfig `mapstructure:"resource"`
+
+	// Attributes configures which flowfile attributes are kept on spans and how values are redacted
+	Attributes AttributesConfig `mapstructure:"attributes"`
+
+	// SpanContextStore configures how long and where the span context of each flowfile is tracked
+	SpanContextStore SpanContextStoreConfig `mapstructure:"span_context_store"`
+
+	// Deduplication configures dropping events redelivered by NiFi, e.g. when a reporting task retries a batch
+	Deduplication DeduplicationConfig `mapstructure:"deduplication"`
+
+	// BulletinHold configures holding the bulletins reported before the provenance events of their flowfile
+	BulletinHold BulletinHoldConfig `mapstructure:"bulletin_hold"`
+
+	// WAL configures the write-ahead log persisting pushed batches before they are acknowledged
+	WAL WALConfig `mapstructure:"wal"`
+
+	// MemoryLimiterID is a memory limiter extension checked before accepting pushed events
+	MemoryLimiterID *component.ID `mapstructure:"memory_limiter,omitempty"`
+
+	// API configures polling the NiFi REST API instead of waiting for reporting tasks to push events
+	API APIConfig `mapstructure:"api"`
+
+	// SiteToSite configures the Site-to-Site HTTP transport server, allowing reporting tasks to deliver directly
+	SiteToSite SiteToSiteConfig `mapstructure:"site_to_site"`
+}
+
+// SiteToSiteConfig configures the Site-to-Site input ports exposed under /nifi-api
+type SiteToSiteConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// ProvenancePortName is the name of the input port receiving provenance events
+	ProvenancePortName string `mapstructure:"provenance_port_name"`
+
+	// BulletinPortName is the name of the input port receiving bulletins
+	BulletinPortName string `mapstructure:"bulletin_port_name"`
+
+	// AdvertisedAddress is the host:port reported to NiFi as the peer to send to,
+	// defaults to the address NiFi used to reach the receiver
+	AdvertisedAddress string `mapstructure:"advertised_address,omitempty"`
+
+	// TransactionTTL is how long an idle transaction is kept before it is discarded
+	TransactionTTL time.Duration `mapstructure:"transaction_ttl"`
+}
+
+// QueueConfig configures the bounded in-memory queue between the HTTP handlers and the pipelines
+type QueueConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Size is the maximum number of queued batches
+	Size int `mapstructure:"size"`
+
+	// NumWorkers is the number of workers draining the queue
+	NumWorkers int `mapstructure:"num_workers"`
+
+	// RetryAfter is the delay suggested to NiFi in the Retry-After header when the receiver is overloaded
+	RetryAfter time.Duration `mapstructure:"retry_after"`
+
+	// FullStatusCode is the status returned when the receiver is overloaded, either 429 or 503
+	FullStatusCode int `mapstructure:"full_status_code"`
+}
+
+// TracesConfig configures how provenance events are assembled into traces
+type TracesConfig struct {
+	// ChainEvents makes each event's span the parent of the next event of the same flowfile,
+	// so the trace shows the path the flowfile took through the processors
+	ChainEvents bool `mapstructure:"chain_events"`
+
+	// RootSpan synthesizes a root span per flowfile, from its CREATE or RECEIVE event to its DROP or EXPIRE event
+	RootSpan bool `mapstructure:"root_span"`
+}
+
+// LineageConfig configures the trace topology of fan-out and fan-in
+type LineageConfig struct {
+	// Fork is the strategy for the children of FORK and CLONE events, either same_trace or new_trace_linked
+	Fork translator.ForkStrategy `mapstructure:"fork"`
+
+	// Join is the strategy for the flowfile created by JOIN events, either new_trace_linked,
+	// adopt_first_parent or adopt_oldest_lineage
+	Join translator.JoinStrategy `mapstructure:"join"`
+}
+
+// ResourceConfig configures how events are grouped into resources
+type ResourceConfig struct {
+	// ServiceName is a text/template over the event's fields rendering service.name,
+	// the process group name is used when empty
+	ServiceName string `mapstructure:"service_name"`
+
+	// Keys are event fields added to the resource, events are grouped by service.name and their values
+	Keys []string `mapstructure:"keys"`
+}
+
+// AttributesConfig filters and redacts flowfile attributes, the event details, the transit
+// uri and bulletin messages before they leave the receiver
+type AttributesConfig struct {
+	// Include keeps only the flowfile attributes matching a pattern, all of them when empty.
+	// Patterns are globs, or regular expressions prefixed with "regex:"
+	Include []string `mapstructure:"include"`
+
+	// Exclude drops the flowfile attributes matching a pattern
+	Exclude []string `mapstructure:"exclude"`
+
+	// Redact rewrites the values of the matching keys, the first matching rule wins
+	Redact []RedactionRuleConfig `mapstructure:"redact"`
+
+	// HashSalt is prepended to the values hashed by the hash action
+	HashSalt configopaque.String `mapstructure:"hash_salt"`
+
+	// RecordChanges records the attributes added, changed or removed by each event as an
+	// attributes.changed span event with their old and new values
+	RecordChanges bool `mapstructure:"record_changes"`
+
+	// OnSpans copies the flowfile attributes to the spans as nifi.attributes.*, defaults to
+	// true unless RecordChanges is set, the changes are then the only attributes on spans
+	OnSpans *bool `mapstructure:"on_spans"`
+}
+
+// onSpans returns true if the flowfile attributes are copied to the spans
+func (cfg AttributesConfig) onSpans() bool {
+	if cfg.OnSpans == nil {
+		return !cfg.RecordChanges
+	}
+	return *cfg.OnSpans
+}
+
+// RedactionRuleConfig rewrites the values of the matching keys
+type RedactionRuleConfig struct {
+	// Keys are patterns over flowfile attribute names, nifi.event.details, nifi.transit.uri and nifi.bulletin.message
+	Keys []string `mapstructure:"keys"`
+
+	// Action is either mask, hash or truncate
+	Action translator.RedactionAction `mapstructure:"action"`
+
+	// MaxLength is the length values are truncated to by the truncate action
+	MaxLength int `mapstructure:"max_length"`
+}
+
+// filterSettings returns the translator settings of the attributes config
+func (cfg AttributesConfig) filterSettings() translator.AttributeFilterSettings {
+	settings := translator.AttributeFilterSettings{
+		Include:  cfg.Include,
+		Exclude:  cfg.Exclude,
+		HashSalt: string(cfg.HashSalt),
+	}
+	for _, rule := range cfg.Redact {
+		settings.Redact = append(settings.Redact, translator.RedactionRule{
+			Keys:      rule.Keys,
+			Action:    rule.Action,
+			MaxLength: rule.MaxLength,
+		})
+	}
+	return settings
+}
+
+// SpanContextStoreConfig configures the store tracking the span context of each flowfile between batches
+type SpanContextStoreConfig struct {
+	// TTL is how long the span context of a flowfile is kept after its last event
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// MaxEntries is the maximum number of flowfiles tracked in memory, the least recently used are evicted first
+	MaxEntries int `mapstructure:"max_entries"`
+
+	// ExpiryInterval is the interval between two removals of the expired span contexts
+	ExpiryInterval time.Duration `mapstructure:"expiry_interval"`
+
+	// StorageID is a storage extension persisting the span contexts so flowfiles keep their trace across restarts
+	StorageID *component.ID `mapstructure:"storage,omitempty"`
+}
+
+// DeduplicationConfig configures the set of recently seen events used to drop redeliveries
+type DeduplicationConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// TTL is how long a seen event is remembered
+	TTL time.Duration `mapstructure:"ttl"`
+
+	// MaxEntries is the maximum number of events remembered per signal, the oldest are forgotten first
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// BulletinHoldConfig configures the buffer holding bulletins until their flowfile's span is translated
+type BulletinHoldConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Timeout is how long a bulletin is held before it is translated without its flowfile's span
+	Timeout time.Duration `mapstructure:"timeout"`
+
+	// MaxEntries is the maximum number of held bulletins, bulletins are translated right away when full
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// WALConfig configures where the write-ahead log is persisted, either a storage extension or a local directory
+type WALConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// StorageID is the storage extension holding the log, e.g. file_storage
+	StorageID *component.ID `mapstructure:"storage,omitempty"`
+
+	// Directory is a local directory holding the log as segment files
+	Directory string `mapstructure:"directory,omitempty"`
+
+	// RetryInterval is the interval between two retries of the batches left pending after a transient failure
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+}
+
+// APIConfig configures the NiFi REST API client used in pull mode
+type APIConfig struct {
+	confighttp.ClientConfig `mapstructure:",squash"`
+
+	// CollectionInterval is the interval between two polls of the API
+	CollectionInterval time.Duration `mapstructure:"collection_interval"`
+
+	// StorageID is the storage extension used to persist the polling cursors,
+	// when unset the cursors are kept in memory and lost on restart
+	StorageID *component.ID `mapstructure:"storage"`
+
+	// Timezone is the IANA time zone of the NiFi instance, e.g. Europe/Berlin. The API reports
+	// times with a zone abbreviation only, which can't be resolved to an offset without it
+	Timezone string `mapstructure:"timezone"`
+
+	Provenance ProvenancePollConfig `mapstructure:"provenance"`
+	Bulletins  BulletinPollConfig   `mapstructure:"bulletins"`
+}
+
+// location returns the time zone of the NiFi instance, UTC when unset
+func (cfg APIConfig) location() *time.Location {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ProvenancePollConfig configures polling of the /nifi-api/provenance endpoint
+type ProvenancePollConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// MaxResults is the maximum number of events requested per provenance query
+	MaxResults int `mapstructure:"max_results"`
+
+	// QueryTimeout is how long to wait for NiFi to complete a provenance query
+	QueryTimeout time.Duration `mapstructure:"query_timeout"`
+}
+
+// BulletinPollConfig configures polling of the /nifi-api/flow/bulletin-board endpoint
+type BulletinPollConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+
+	// Limit is the maximum number of bulletins requested per call
+	Limit int `mapstructure:"limit"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+// Validate checks the receiver configuration is valid
+func (cfg *Config) Validate() error {
+	if cfg.StreamingFlushSize < 0 {
+		return errors.New("streaming_flush_size must not be negative")
+	}
+
+	if cfg.Queue.Enabled {
+		if cfg.Queue.Size <= 0 || cfg.Queue.NumWorkers <= 0 {
+			return errors.New("queue.size and queue.num_workers must be positive")
+		}
+
+		if cfg.Queue.FullStatusCode != http.StatusTooManyRequests && cfg.Queue.FullStatusCode != http.StatusServiceUnavailable {
+			return errors.New("queue.full_status_code must be either 429 or 503")
+		}
+	}
+
+	switch cfg.Lineage.Fork {
+	case translator.ForkSameTrace, translator.ForkNewTraceLinked:
+	default:
+		return fmt.Errorf("lineage.fork must be either %s or %s", translator.ForkSameTrace, translator.ForkNewTraceLinked)
+	}
+
+	switch cfg.Lineage.Join {
+	case translator.JoinNewTraceLinked, translator.JoinAdoptFirstParent, translator.JoinAdoptOldestLineage:
+	default:
+		return fmt.Errorf("lineage.join must be either %s, %s or %s", translator.JoinNewTraceLinked, translator.JoinAdoptFirstParent, translator.JoinAdoptOldestLineage)
+	}
+
+	if cfg.Resource.ServiceName != "" {
+		if _, err := translator.ParseServiceNameTemplate(cfg.Resource.ServiceName); err != nil {
+			return fmt.Errorf("resource.service_name is not a valid template: %w", err)
+		}
+	}
+
+	for _, key := range cfg.Resource.Keys {
+		if err := translator.ValidateResourceKey(key); err != nil {
+			return fmt.Errorf("resource.keys: %w", err)
+		}
+	}
+
+	if _, err := translator.NewAttributeFilter(cfg.Attributes.filterSettings()); err != nil {
+		return fmt.Errorf("attributes: %w", err)
+	}
+
+	if cfg.SpanContextStore.TTL <= 0 || cfg.SpanContextStore.MaxEntries <= 0 || cfg.SpanContextStore.ExpiryInterval <= 0 {
+		return errors.New("span_context_store.ttl, span_context_store.max_entries and span_context_store.expiry_interval must be positive")
+	}
+
+	if cfg.Deduplication.Enabled && (cfg.Deduplication.TTL <= 0 || cfg.Deduplication.MaxEntries <= 0) {
+		return errors.New("deduplication.ttl and deduplication.max_entries must be positive")
+	}
+
+	if cfg.BulletinHold.Enabled && (cfg.BulletinHold.Timeout <= 0 || cfg.BulletinHold.MaxEntries <= 0) {
+		return errors.New("bulletin_hold.timeout and bulletin_hold.max_entries must be positive")
+	}
+
+	if cfg.WAL.Enabled && (cfg.WAL.StorageID == nil) == (cfg.WAL.Directory == "") {
+		return errors.New("exactly one of wal.storage and wal.directory must be set")
+	}
+
+	if cfg.WAL.Enabled && cfg.WAL.RetryInterval <= 0 {
+		return errors.New("wal.retry_interval must be positive")
+	}
+
+	if cfg.SiteToSite.Enabled {
+		if cfg.SiteToSite.ProvenancePortName == "" || cfg.SiteToSite.BulletinPortName == "" {
+			return errors.New("site_to_site port names must be set")
+		}
+
+		if cfg.SiteToSite.ProvenancePortName == cfg.SiteToSite.BulletinPortName {
+			return errors.New("site_to_site port names must be unique")
+		}
+
+		if cfg.SiteToSite.TransactionTTL <= 0 {
+			return errors.New("site_to_site.transaction_ttl must be positive")
+		}
+	}
+
+	if !cfg.API.Provenance.Enabled && !cfg.API.Bulletins.Enabled {
+		return nil
+	}
+
+	if cfg.API.Endpoint == "" {
+		return errors.New("api.endpoint must be set when polling the NiFi API")
+	}
+
+	if cfg.API.CollectionInterval <= 0 {
+		return errors.New("api.collection_interval must be positive")
+	}
+
+	if _, err := time.LoadLocation(cfg.API.Timezone); err != nil {
+		return fmt.Errorf("invalid api.timezone: %w", err)
+	}
+
+	if cfg.API.Provenance.Enabled && cfg.API.Provenance.MaxResults <= 0 {
+		return errors.New("api.provenance.max_results must be positive")
+	}
+
+	if cfg.API.Provenance.Enabled && cfg.API.Provenance.QueryTimeout <= 0 {
+		return errors.New("api.provenance.query_timeout must be positive")
+	}
+
+	if cfg.API.Bulletins.Enabled && cfg.API.Bulletins.Limit <= 0 {
+		return errors.New("api.bulletins.limit must be positive")
+	}
+
+	return nil
 }
@@ -2,8 +2,10 @@ package nifireceiver
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
 )
 
 func TestCreateDefaultConfig(t *testing.T) {
@@ -11,3 +13,75 @@ func TestCreateDefaultConfig(t *testing.T) {
 	cfg := factory.CreateDefaultConfig()
 	assert.NotNil(t, cfg, "failed to create default config")
 }
+
+func TestAttributesConfigOnSpans(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	assert.True(t, cfg.Attributes.onSpans())
+
+	// recording the changes keeps the full attribute set off the spans
+	cfg.Attributes.RecordChanges = true
+	assert.False(t, cfg.Attributes.onSpans())
+
+	onSpans := true
+	cfg.Attributes.OnSpans = &onSpans
+	assert.True(t, cfg.Attributes.onSpans())
+}
+
+func TestValidateConfig(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Lineage.Join = "adopt_last_parent"
+	assert.Error(t, cfg.Validate(), "unknown join strategy")
+
+	cfg.Lineage.Join = translator.JoinAdoptOldestLineage
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Resource.ServiceName = "{{ .RootProcessGroup"
+	assert.Error(t, cfg.Validate(), "invalid service name template")
+
+	cfg.Resource.ServiceName = "{{ .RootProcessGroup }}"
+	cfg.Resource.Keys = []string{"nifi.attributes.tenant", "nifi.node"}
+	assert.Error(t, cfg.Validate(), "unknown resource key")
+
+	cfg.Resource.Keys = []string{"nifi.attributes.tenant", "nifi.hostname"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Attributes.Redact = []RedactionRuleConfig{{Keys: []string{"filename"}, Action: translator.RedactionTruncate}}
+	assert.Error(t, cfg.Validate(), "truncate requires max_length")
+
+	cfg.Attributes.Redact[0].MaxLength = 16
+	cfg.Attributes.Exclude = []string{"regex:http\\.headers\\..*"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.BulletinHold.Enabled = true
+	cfg.BulletinHold.Timeout = 0
+	assert.Error(t, cfg.Validate(), "bulletin hold requires a timeout")
+
+	cfg.BulletinHold.Timeout = 10 * time.Second
+	assert.NoError(t, cfg.Validate())
+
+	cfg.WAL.Enabled = true
+	assert.Error(t, cfg.Validate(), "wal requires a storage extension or a directory")
+
+	cfg.WAL.Directory = t.TempDir()
+	assert.NoError(t, cfg.Validate())
+
+	cfg.WAL.RetryInterval = 0
+	assert.Error(t, cfg.Validate(), "wal retry interval must be positive")
+	cfg.WAL.RetryInterval = time.Second
+
+	cfg.API.Provenance.Enabled = true
+	assert.Error(t, cfg.Validate(), "api endpoint is required when polling")
+
+	cfg.API.Endpoint = "https://nifi:8443"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.API.Timezone = "CEST"
+	assert.Error(t, cfg.Validate(), "api timezone must be an IANA time zone")
+	cfg.API.Timezone = "Europe/Berlin"
+	assert.NoError(t, cfg.Validate())
+
+	cfg.API.Provenance.MaxResults = 0
+	assert.Error(t, cfg.Validate())
+}
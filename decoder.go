@@ -0,0 +1,88 @@
+package nifireceiver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+)
+
+// avroContentTypes are the content types decoded as Avro object container files,
+// any other content type is decoded as JSON
+var avroContentTypes = []string{
+	"avro/binary",
+	"application/avro",
+	"application/avro-binary",
+	"application/vnd.apache.avro+binary",
+}
+
+// isAvro returns true if the content type is one of the Avro content types
+func isAvro(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(avroContentTypes, strings.ToLower(mediaType))
+}
+
+// decodeProvenanceEvents decodes provenance events according to the content type
+func decodeProvenanceEvents(contentType string, body io.Reader) ([]translator.ProvenanceEvent, error) {
+	if isAvro(contentType) {
+		return translator.DecodeAvroProvenanceEvents(body)
+	}
+
+	var events []translator.ProvenanceEvent
+	err := json.NewDecoder(body).Decode(&events)
+	return events, err
+}
+
+// streamProvenanceEvents decodes provenance events one at a time according to the content type,
+// fn is called with each event and decoding stops at the first error
+func streamProvenanceEvents(contentType string, body io.Reader, fn func(translator.ProvenanceEvent) error) error {
+	if isAvro(contentType) {
+		return translator.StreamAvroProvenanceEvents(body, fn)
+	}
+	return streamJSONArray(body, fn)
+}
+
+// streamJSONArray decodes the elements of a JSON array one at a time
+func streamJSONArray[T any](body io.Reader, fn func(T) error) error {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array, got %v", token)
+	}
+
+	for decoder.More() {
+		var element T
+		if err := decoder.Decode(&element); err != nil {
+			return err
+		}
+
+		if err := fn(element); err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// decodeBulletinEvents decodes bulletin events according to the content type
+func decodeBulletinEvents(contentType string, body io.Reader) ([]translator.BulletinEvent, error) {
+	if isAvro(contentType) {
+		return translator.DecodeAvroBulletinEvents(body)
+	}
+
+	var events []translator.BulletinEvent
+	err := json.NewDecoder(body).Decode(&events)
+	return events, err
+}
@@ -2,6 +2,9 @@ package nifireceiver
 
 import (
 	"context"
+	"net/http"
+	"sync"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/config/confighttp"
@@ -12,12 +15,21 @@ import (
 	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
 )
 
-// NewFactory creates a factory for DataDog receiver.
+// receivers holds the receivers created per config, the pipelines of a
+// single nifi receiver share the same HTTP server
+var (
+	receiversMu sync.Mutex
+	receivers   = map[*Config]*nifiReceiver{}
+)
+
+// NewFactory creates a factory for Nifi receiver.
 func NewFactory() receiver.Factory {
 	return receiver.NewFactory(
 		metadata.Type,
 		createDefaultConfig,
-		receiver.WithTraces(createTracesReceiver, metadata.TracesStability))
+		receiver.WithTraces(createTracesReceiver, metadata.TracesStability),
+		receiver.WithMetrics(createMetricsReceiver, metadata.MetricsStability),
+		receiver.WithLogs(createLogsReceiver, metadata.LogsStability))
 }
 
 func createDefaultConfig() component.Config {
@@ -31,10 +43,110 @@ func createDefaultConfig() component.Config {
 		ContextPropagationAliases: map[string]string{},
 		BulletinURLPath:           "/v1/bulletin",
 		ProvenanceURLPath:         "/v1/provenance",
+		Lineage: LineageConfig{
+			Fork: translator.ForkSameTrace,
+			Join: translator.JoinNewTraceLinked,
+		},
+		SpanContextStore: SpanContextStoreConfig{
+			TTL:            5 * time.Minute,
+			MaxEntries:     100000,
+			ExpiryInterval: 30 * time.Second,
+		},
+		Deduplication: DeduplicationConfig{
+			Enabled:    true,
+			TTL:        10 * time.Minute,
+			MaxEntries: 100000,
+		},
+		BulletinHold: BulletinHoldConfig{
+			Timeout:    30 * time.Second,
+			MaxEntries: 10000,
+		},
+		Queue: QueueConfig{
+			Size:           100,
+			NumWorkers:     4,
+			RetryAfter:     5 * time.Second,
+			FullStatusCode: http.StatusServiceUnavailable,
+		},
+		WAL: WALConfig{
+			RetryInterval: 30 * time.Second,
+		},
+		API: APIConfig{
+			ClientConfig:       confighttp.NewDefaultClientConfig(),
+			CollectionInterval: 30 * time.Second,
+			Timezone:           "UTC",
+			Provenance: ProvenancePollConfig{
+				MaxResults:   1000,
+				QueryTimeout: 30 * time.Second,
+			},
+			Bulletins: BulletinPollConfig{
+				Limit: 1000,
+			},
+		},
+		SiteToSite: SiteToSiteConfig{
+			ProvenancePortName: "provenance",
+			BulletinPortName:   "bulletins",
+			TransactionTTL:     30 * time.Second,
+		},
 	}
 }
 
 func createTracesReceiver(_ context.Context, params receiver.CreateSettings, cfg component.Config, consumer consumer.Traces) (receiver.Traces, error) {
-	rcfg := cfg.(*Config)
-	return newNifiReceiver(rcfg, consumer, params)
+	r, err := getOrCreateReceiver(cfg.(*Config), params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.registerTracesConsumer(consumer); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func createMetricsReceiver(_ context.Context, params receiver.CreateSettings, cfg component.Config, consumer consumer.Metrics) (receiver.Metrics, error) {
+	r, err := getOrCreateReceiver(cfg.(*Config), params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.registerMetricsConsumer(consumer); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func createLogsReceiver(_ context.Context, params receiver.CreateSettings, cfg component.Config, consumer consumer.Logs) (receiver.Logs, error) {
+	r, err := getOrCreateReceiver(cfg.(*Config), params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.registerLogsConsumer(consumer); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// getOrCreateReceiver returns the receiver already created for the config, or creates a new one
+func getOrCreateReceiver(cfg *Config, params receiver.CreateSettings) (*nifiReceiver, error) {
+	receiversMu.Lock()
+	defer receiversMu.Unlock()
+
+	if r, ok := receivers[cfg]; ok {
+		return r, nil
+	}
+
+	r, err := newNifiReceiver(cfg, params)
+	if err != nil {
+		return nil, err
+	}
+
+	receivers[cfg] = r
+	return r, nil
+}
+
+// removeReceiver forgets the receiver created for the config
+func removeReceiver(cfg *Config) {
+	receiversMu.Lock()
+	defer receiversMu.Unlock()
+	delete(receivers, cfg)
 }
@@ -4,10 +4,13 @@ go 1.21
 
 require (
 	github.com/google/uuid v1.4.0
+	github.com/linkedin/goavro/v2 v2.12.0
 	github.com/stretchr/testify v1.8.4
 	go.opentelemetry.io/collector/component v0.95.0
 	go.opentelemetry.io/collector/config/confighttp v0.95.0
+	go.opentelemetry.io/collector/config/configopaque v1.2.0
 	go.opentelemetry.io/collector/consumer v0.95.0
+	go.opentelemetry.io/collector/extension v0.95.0
 	go.opentelemetry.io/collector/pdata v1.2.0
 	go.opentelemetry.io/collector/receiver v0.95.0
 	go.opentelemetry.io/otel v1.24.0
@@ -48,12 +51,10 @@ require (
 	go.opentelemetry.io/collector v0.95.0 // indirect
 	go.opentelemetry.io/collector/config/configauth v0.95.0 // indirect
 	go.opentelemetry.io/collector/config/configcompression v0.95.0 // indirect
-	go.opentelemetry.io/collector/config/configopaque v1.2.0 // indirect
 	go.opentelemetry.io/collector/config/configtelemetry v0.95.0 // indirect
 	go.opentelemetry.io/collector/config/configtls v0.95.0 // indirect
 	go.opentelemetry.io/collector/config/internal v0.95.0 // indirect
 	go.opentelemetry.io/collector/confmap v0.95.0 // indirect
-	go.opentelemetry.io/collector/extension v0.95.0 // indirect
 	go.opentelemetry.io/collector/extension/auth v0.95.0 // indirect
 	go.opentelemetry.io/collector/featuregate v1.2.0 // indirect
 	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.47.0 // indirect
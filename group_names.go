@@ -0,0 +1,74 @@
+package nifireceiver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+)
+
+// groupNameRetryInterval is how long a process group is not looked up again after a failure
+const groupNameRetryInterval = time.Minute
+
+// groupNameCache resolves process group names through the NiFi API, the REST API
+// only returns group ids, names are cached for the lifetime of the receiver
+type groupNameCache struct {
+	logger *zap.Logger
+	client *nifiapi.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	names map[string]string
+
+	// failures is when each failed lookup can be retried
+	failures map[string]time.Time
+}
+
+func newGroupNameCache(logger *zap.Logger, client *nifiapi.Client) *groupNameCache {
+	return &groupNameCache{
+		logger:   logger,
+		client:   client,
+		now:      time.Now,
+		names:    make(map[string]string),
+		failures: make(map[string]time.Time),
+	}
+}
+
+// get returns the name of the process group, or an empty string if it can't be resolved.
+// Failed lookups are retried after groupNameRetryInterval
+func (c *groupNameCache) get(ctx context.Context, groupID string) string {
+	if groupID == "" {
+		return ""
+	}
+
+	c.mu.Lock()
+	name, ok := c.names[groupID]
+	retry, failed := c.failures[groupID]
+	c.mu.Unlock()
+
+	if ok {
+		return name
+	}
+	if failed && c.now().Before(retry) {
+		return ""
+	}
+
+	// the API is called without the lock so a slow lookup doesn't block the others
+	group, err := c.client.GetProcessGroup(ctx, groupID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.logger.Debug("failed to resolve process group name", zap.String("group.id", groupID), zap.Error(err))
+		c.failures[groupID] = c.now().Add(groupNameRetryInterval)
+		return ""
+	}
+
+	delete(c.failures, groupID)
+	c.names[groupID] = group.Component.Name
+	return group.Component.Name
+}
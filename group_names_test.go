@@ -0,0 +1,43 @@
+package nifireceiver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+)
+
+func TestGroupNameCache(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(nifiapi.ProcessGroupEntity{Component: nifiapi.ProcessGroupDTO{Name: "ingest"}})
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := newGroupNameCache(zap.NewNop(), nifiapi.NewClient(server.Client(), server.URL))
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// a failed lookup isn't retried right away, nor cached for good
+	assert.Empty(t, cache.get(ctx, "group"))
+	assert.Empty(t, cache.get(ctx, "group"))
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(groupNameRetryInterval)
+	assert.Equal(t, "ingest", cache.get(ctx, "group"))
+	assert.Equal(t, "ingest", cache.get(ctx, "group"))
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,133 @@
+package nifiapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DateFormat is the layout of the dates accepted by provenance queries
+const DateFormat = "01/02/2006 15:04:05 MST"
+
+// EventTimeFormat is the layout of the event times returned by provenance queries
+const EventTimeFormat = "01/02/2006 15:04:05.000 MST"
+
+// BulletinTimeFormat is the layout of the bulletin timestamps, bulletins only carry the time of day
+const BulletinTimeFormat = "15:04:05 MST"
+
+// Client is a minimal client for the NiFi REST API
+type Client struct {
+	httpClient *http.Client
+	endpoint   string
+}
+
+// NewClient creates a new client for the NiFi instance at endpoint (e.g. https://nifi:8443)
+func NewClient(httpClient *http.Client, endpoint string) *Client {
+	return &Client{
+		httpClient: httpClient,
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+	}
+}
+
+// SubmitProvenanceQuery submits a new provenance query, the query runs asynchronously
+// and should be polled with GetProvenanceQuery until it is finished
+func (c *Client) SubmitProvenanceQuery(ctx context.Context, request ProvenanceRequestDTO) (*ProvenanceDTO, error) {
+	var entity ProvenanceEntity
+	body := ProvenanceEntity{Provenance: ProvenanceDTO{Request: request}}
+	if err := c.do(ctx, http.MethodPost, "/nifi-api/provenance", nil, body, &entity); err != nil {
+		return nil, err
+	}
+	return &entity.Provenance, nil
+}
+
+// GetProvenanceQuery returns the current state of a provenance query
+func (c *Client) GetProvenanceQuery(ctx context.Context, id string) (*ProvenanceDTO, error) {
+	var entity ProvenanceEntity
+	query := url.Values{"summarize": {"false"}, "incrementalResults": {"false"}}
+	if err := c.do(ctx, http.MethodGet, "/nifi-api/provenance/"+url.PathEscape(id), query, nil, &entity); err != nil {
+		return nil, err
+	}
+	return &entity.Provenance, nil
+}
+
+// DeleteProvenanceQuery releases the resources held by a provenance query
+func (c *Client) DeleteProvenanceQuery(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/nifi-api/provenance/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// GetProcessGroup returns a process group by id
+func (c *Client) GetProcessGroup(ctx context.Context, id string) (*ProcessGroupEntity, error) {
+	var entity ProcessGroupEntity
+	if err := c.do(ctx, http.MethodGet, "/nifi-api/process-groups/"+url.PathEscape(id), nil, nil, &entity); err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// GetBulletinBoard returns up to limit bulletins with an id greater than after, a negative after returns all bulletins
+func (c *Client) GetBulletinBoard(ctx context.Context, after int64, limit int) (*BulletinBoardDTO, error) {
+	var entity BulletinBoardEntity
+	query := url.Values{"limit": {strconv.Itoa(limit)}}
+	if after >= 0 {
+		query.Set("after", strconv.FormatInt(after, 10))
+	}
+
+	if err := c.do(ctx, http.MethodGet, "/nifi-api/flow/bulletin-board", query, nil, &entity); err != nil {
+		return nil, err
+	}
+	return &entity.BulletinBoard, nil
+}
+
+// do sends a request to the NiFi API, encoding body and decoding the response into out when set
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s returned %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
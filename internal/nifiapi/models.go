@@ -0,0 +1,116 @@
+package nifiapi
+
+// ProvenanceEntity wraps a provenance query, as returned by /nifi-api/provenance
+type ProvenanceEntity struct {
+	Provenance ProvenanceDTO `json:"provenance"`
+}
+
+// ProvenanceDTO is a provenance query and its results
+type ProvenanceDTO struct {
+	ID               string                `json:"id,omitempty"`
+	URI              string                `json:"uri,omitempty"`
+	Finished         bool                  `json:"finished,omitempty"`
+	PercentCompleted int                   `json:"percentCompleted,omitempty"`
+	Request          ProvenanceRequestDTO  `json:"request"`
+	Results          *ProvenanceResultsDTO `json:"results,omitempty"`
+}
+
+// ProvenanceRequestDTO describes the provenance query to run
+type ProvenanceRequestDTO struct {
+	MaxResults         int    `json:"maxResults,omitempty"`
+	StartDate          string `json:"startDate,omitempty"` // Format: MM/dd/yyyy HH:mm:ss z
+	EndDate            string `json:"endDate,omitempty"`   // Format: MM/dd/yyyy HH:mm:ss z
+	ClusterNodeID      string `json:"clusterNodeId,omitempty"`
+	Summarize          bool   `json:"summarize"`
+	IncrementalResults bool   `json:"incrementalResults"`
+}
+
+// ProvenanceResultsDTO holds the results of a provenance query
+type ProvenanceResultsDTO struct {
+	ProvenanceEvents []ProvenanceEventDTO `json:"provenanceEvents,omitempty"`
+	TotalCount       int64                `json:"totalCount,omitempty"`
+	Errors           []string             `json:"errors,omitempty"`
+}
+
+// ProvenanceEventDTO is a single provenance event as returned by the REST API
+type ProvenanceEventDTO struct {
+	ID                             string         `json:"id,omitempty"`
+	EventID                        int64          `json:"eventId"`
+	EventTime                      string         `json:"eventTime,omitempty"` // Format: MM/dd/yyyy HH:mm:ss.SSS z
+	EventDuration                  *int64         `json:"eventDuration,omitempty"`
+	LineageDuration                *int64         `json:"lineageDuration,omitempty"`
+	EventType                      string         `json:"eventType,omitempty"`
+	FlowFileUUID                   string         `json:"flowFileUuid,omitempty"`
+	FileSizeBytes                  int64          `json:"fileSizeBytes,omitempty"`
+	ClusterNodeID                  string         `json:"clusterNodeId,omitempty"`
+	ClusterNodeAddress             string         `json:"clusterNodeAddress,omitempty"`
+	GroupID                        string         `json:"groupId,omitempty"`
+	ComponentID                    string         `json:"componentId,omitempty"`
+	ComponentType                  string         `json:"componentType,omitempty"`
+	ComponentName                  string         `json:"componentName,omitempty"`
+	SourceSystemFlowFileID         string         `json:"sourceSystemFlowFileId,omitempty"`
+	AlternateIdentifierURI         string         `json:"alternateIdentifierUri,omitempty"`
+	Attributes                     []AttributeDTO `json:"attributes,omitempty"`
+	ParentUUIDs                    []string       `json:"parentUuids,omitempty"`
+	ChildUUIDs                     []string       `json:"childUuids,omitempty"`
+	TransitURI                     string         `json:"transitUri,omitempty"`
+	Details                        string         `json:"details,omitempty"`
+	InputContentClaimFileSizeBytes *int64         `json:"inputContentClaimFileSizeBytes,omitempty"`
+}
+
+// AttributeDTO is a flowfile attribute of a provenance event
+type AttributeDTO struct {
+	Name          string  `json:"name"`
+	Value         *string `json:"value,omitempty"`
+	PreviousValue *string `json:"previousValue,omitempty"`
+}
+
+// ProcessGroupEntity wraps a process group, as returned by /nifi-api/process-groups/{id}
+type ProcessGroupEntity struct {
+	ID        string          `json:"id"`
+	Component ProcessGroupDTO `json:"component"`
+}
+
+// ProcessGroupDTO describes a process group
+type ProcessGroupDTO struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ParentGroupID string `json:"parentGroupId,omitempty"`
+}
+
+// BulletinBoardEntity wraps the bulletin board, as returned by /nifi-api/flow/bulletin-board
+type BulletinBoardEntity struct {
+	BulletinBoard BulletinBoardDTO `json:"bulletinBoard"`
+}
+
+// BulletinBoardDTO holds the bulletins currently on the board
+type BulletinBoardDTO struct {
+	Bulletins []BulletinEntity `json:"bulletins,omitempty"`
+	Generated string           `json:"generated,omitempty"` // Format: HH:mm:ss z
+}
+
+// BulletinEntity wraps a single bulletin
+type BulletinEntity struct {
+	ID          int64        `json:"id"`
+	GroupID     string       `json:"groupId,omitempty"`
+	SourceID    string       `json:"sourceId,omitempty"`
+	Timestamp   string       `json:"timestamp,omitempty"` // Format: HH:mm:ss z
+	NodeAddress string       `json:"nodeAddress,omitempty"`
+	CanRead     bool         `json:"canRead,omitempty"`
+	Bulletin    *BulletinDTO `json:"bulletin,omitempty"`
+}
+
+// BulletinDTO is a single bulletin as returned by the REST API
+type BulletinDTO struct {
+	ID           int64  `json:"id"`
+	NodeAddress  string `json:"nodeAddress,omitempty"`
+	Category     string `json:"category,omitempty"`
+	GroupID      string `json:"groupId,omitempty"`
+	SourceID     string `json:"sourceId,omitempty"`
+	SourceName   string `json:"sourceName,omitempty"`
+	SourceType   string `json:"sourceType,omitempty"`
+	FlowFileUUID string `json:"flowFileUuid,omitempty"`
+	Level        string `json:"level,omitempty"`
+	Message      string `json:"message,omitempty"`
+	Timestamp    string `json:"timestamp,omitempty"` // Format: HH:mm:ss z
+}
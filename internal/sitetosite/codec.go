@@ -0,0 +1,176 @@
+package sitetosite
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// maxAttributes bounds the number of attributes of a flowfile
+	maxAttributes = 10000
+
+	// maxAttributesLength bounds the total size of the attribute keys and values of a flowfile
+	maxAttributesLength = 64 * 1024 * 1024
+)
+
+// DataPacket is a single flowfile transferred over Site-to-Site
+type DataPacket struct {
+	Attributes map[string]string
+	Content    []byte
+}
+
+// DecodePackets decodes a stream of flowfile packets, each packet is encoded as
+// the attribute count, the attributes as length prefixed UTF-8 strings, followed
+// by the content length and the content bytes. Socket based clients separate the
+// packets with CONTINUE_TRANSACTION/FINISH_TRANSACTION response codes, those are
+// skipped when present.
+func DecodePackets(r io.Reader) ([]DataPacket, error) {
+	br := bufio.NewReader(r)
+
+	var packets []DataPacket
+	for {
+		code, ok, err := peekResponseCode(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return packets, nil
+			}
+			return nil, err
+		}
+
+		if ok {
+			if code == ResponseCodeFinishTransaction {
+				return packets, nil
+			}
+			if code != ResponseCodeContinueTransaction {
+				return nil, fmt.Errorf("unexpected response code %d in packet stream", code)
+			}
+			continue
+		}
+
+		packet, err := decodePacket(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode packet %d: %w", len(packets), err)
+		}
+		packets = append(packets, packet)
+	}
+}
+
+// EncodePacket encodes a single flowfile packet
+func EncodePacket(w io.Writer, packet DataPacket) error {
+	if err := binary.Write(w, binary.BigEndian, int32(len(packet.Attributes))); err != nil {
+		return err
+	}
+
+	for k, v := range packet.Attributes {
+		if err := writeString(w, k); err != nil {
+			return err
+		}
+		if err := writeString(w, v); err != nil {
+			return err
+		}
+	}
+
+	if err := binary.Write(w, binary.BigEndian, int64(len(packet.Content))); err != nil {
+		return err
+	}
+
+	_, err := w.Write(packet.Content)
+	return err
+}
+
+// peekResponseCode consumes a response code ("RC" followed by the code) if one is next in the stream,
+// an attribute count starting with these bytes would be unreasonably large so there is no ambiguity
+func peekResponseCode(br *bufio.Reader) (ResponseCode, bool, error) {
+	header, err := br.Peek(3)
+	if err != nil {
+		if len(header) == 0 {
+			return 0, false, io.EOF
+		}
+		return 0, false, fmt.Errorf("truncated packet stream: %w", io.ErrUnexpectedEOF)
+	}
+
+	if header[0] != 'R' || header[1] != 'C' {
+		return 0, false, nil
+	}
+
+	_, _ = br.Discard(3)
+	return ResponseCode(header[2]), true, nil
+}
+
+func decodePacket(r io.Reader) (DataPacket, error) {
+	var count int32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return DataPacket{}, fmt.Errorf("failed to read attribute count: %w", err)
+	}
+
+	if count < 0 || count > maxAttributes {
+		return DataPacket{}, fmt.Errorf("invalid attribute count %d", count)
+	}
+
+	packet := DataPacket{Attributes: make(map[string]string, count)}
+	remaining := int32(maxAttributesLength)
+	for i := int32(0); i < count; i++ {
+		key, err := readString(r, remaining)
+		if err != nil {
+			return DataPacket{}, fmt.Errorf("failed to read attribute key: %w", err)
+		}
+		remaining -= int32(len(key))
+
+		value, err := readString(r, remaining)
+		if err != nil {
+			return DataPacket{}, fmt.Errorf("failed to read attribute value: %w", err)
+		}
+		remaining -= int32(len(value))
+		packet.Attributes[key] = value
+	}
+
+	var length int64
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return DataPacket{}, fmt.Errorf("failed to read content length: %w", err)
+	}
+
+	if length < 0 {
+		return DataPacket{}, fmt.Errorf("invalid content length %d", length)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, length))
+	if err != nil {
+		return DataPacket{}, fmt.Errorf("failed to read content: %w", err)
+	}
+
+	if int64(len(content)) != length {
+		return DataPacket{}, fmt.Errorf("failed to read content: %w", io.ErrUnexpectedEOF)
+	}
+
+	packet.Content = content
+	return packet, nil
+}
+
+// readString reads a length prefixed string of at most limit bytes
+func readString(r io.Reader, limit int32) (string, error) {
+	var length int32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+
+	if length < 0 || length > limit {
+		return "", fmt.Errorf("invalid string length %d", length)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func writeString(w io.Writer, value string) error {
+	if err := binary.Write(w, binary.BigEndian, int32(len(value))); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, value)
+	return err
+}
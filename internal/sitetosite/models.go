@@ -0,0 +1,73 @@
+package sitetosite
+
+// ResponseCode is a Site-to-Site protocol response code
+type ResponseCode int
+
+const (
+	ResponseCodePropertiesOK        ResponseCode = 1
+	ResponseCodeContinueTransaction ResponseCode = 10
+	ResponseCodeFinishTransaction   ResponseCode = 11
+	ResponseCodeConfirmTransaction  ResponseCode = 12
+	ResponseCodeTransactionFinished ResponseCode = 13
+	ResponseCodeCancelTransaction   ResponseCode = 15
+	ResponseCodeBadChecksum         ResponseCode = 19
+)
+
+// HTTP headers used by the Site-to-Site HTTP transport
+const (
+	HeaderProtocolVersion    = "x-nifi-site-to-site-protocol-version"
+	HeaderServerSideTTL      = "x-nifi-site-to-site-server-transaction-ttl"
+	HeaderLocationURIIntent  = "x-location-uri-intent"
+	HeaderUseCompression     = "x-nifi-site-to-site-use-compression"
+	LocationURIIntentTxURL   = "transaction-url"
+	SupportedProtocolVersion = "1"
+)
+
+// ControllerEntity is returned by /nifi-api/site-to-site
+type ControllerEntity struct {
+	Controller ControllerDTO `json:"controller"`
+}
+
+// ControllerDTO describes this instance and its input ports
+type ControllerDTO struct {
+	ID                          string    `json:"id"`
+	Name                        string    `json:"name"`
+	Comments                    string    `json:"comments"`
+	RunningCount                int       `json:"runningCount"`
+	InputPortCount              int       `json:"inputPortCount"`
+	OutputPortCount             int       `json:"outputPortCount"`
+	RemoteSiteListeningPort     *int      `json:"remoteSiteListeningPort"`
+	RemoteSiteHTTPListeningPort *int      `json:"remoteSiteHttpListeningPort"`
+	SiteToSiteSecure            bool      `json:"siteToSiteSecure"`
+	InstanceID                  string    `json:"instanceId"`
+	InputPorts                  []PortDTO `json:"inputPorts"`
+	OutputPorts                 []PortDTO `json:"outputPorts"`
+}
+
+// PortDTO describes a Site-to-Site port
+type PortDTO struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Comments string `json:"comments"`
+	State    string `json:"state"`
+}
+
+// PeersEntity is returned by /nifi-api/site-to-site/peers
+type PeersEntity struct {
+	Peers []PeerDTO `json:"peers"`
+}
+
+// PeerDTO describes a node that can receive Site-to-Site transactions
+type PeerDTO struct {
+	Hostname      string `json:"hostname"`
+	Port          int    `json:"port"`
+	Secure        bool   `json:"secure"`
+	FlowFileCount int    `json:"flowFileCount"`
+}
+
+// TransactionResultEntity is returned by the transaction endpoints
+type TransactionResultEntity struct {
+	FlowFileSent int          `json:"flowFileSent"`
+	ResponseCode ResponseCode `json:"responseCode"`
+	Message      string       `json:"message,omitempty"`
+}
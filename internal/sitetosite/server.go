@@ -0,0 +1,317 @@
+package sitetosite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	siteToSitePath   = "/nifi-api/site-to-site"
+	peersPath        = "/nifi-api/site-to-site/peers"
+	inputPortsPrefix = "/nifi-api/data-transfer/input-ports/"
+)
+
+// portNamespace is used to derive stable port ids from port names
+var portNamespace = uuid.MustParse("9b6f0a52-3c1e-4f0b-8d6c-2a8e5f4b7c13")
+
+// Port is an input port exposed over Site-to-Site
+type Port struct {
+	ID   string
+	Name string
+}
+
+// ReceiveFunc is called with the packets of a confirmed transaction,
+// the transaction is reported as finished to the client only when it returns nil
+type ReceiveFunc func(ctx context.Context, port Port, packets []DataPacket) error
+
+// Settings configures the Site-to-Site server
+type Settings struct {
+	// Name is the instance name reported to clients
+	Name string
+
+	// PortNames are the names of the input ports to expose
+	PortNames []string
+
+	// AdvertisedAddress is the host:port reported to clients as the only peer,
+	// defaults to the host the client connected to
+	AdvertisedAddress string
+
+	// TransactionTTL is how long an idle transaction is kept before it is discarded
+	TransactionTTL time.Duration
+}
+
+type transaction struct {
+	port    Port
+	packets []DataPacket
+	// checksum is the CRC32 of all the data received by the transaction, over all its requests
+	checksum  uint32
+	expiresAt time.Time
+}
+
+// Server implements the server side of the Site-to-Site HTTP transport, only input ports are supported
+type Server struct {
+	logger     *zap.Logger
+	settings   Settings
+	instanceID string
+	ports      map[string]Port
+	receive    ReceiveFunc
+
+	mu           sync.Mutex
+	transactions map[string]*transaction
+}
+
+// NewServer creates a new Site-to-Site server
+func NewServer(logger *zap.Logger, settings Settings, receive ReceiveFunc) *Server {
+	ports := make(map[string]Port, len(settings.PortNames))
+	for _, name := range settings.PortNames {
+		port := Port{ID: PortID(name), Name: name}
+		ports[port.ID] = port
+	}
+
+	return &Server{
+		logger:       logger,
+		settings:     settings,
+		instanceID:   uuid.NewSHA1(portNamespace, []byte(settings.Name)).String(),
+		ports:        ports,
+		receive:      receive,
+		transactions: make(map[string]*transaction),
+	}
+}
+
+// PortID returns the id of the input port with the given name
+func PortID(name string) string {
+	return uuid.NewSHA1(portNamespace, []byte(name)).String()
+}
+
+// ServeHTTP routes the Site-to-Site requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set(HeaderProtocolVersion, SupportedProtocolVersion)
+	s.expireTransactions()
+
+	switch {
+	case req.URL.Path == siteToSitePath && req.Method == http.MethodGet:
+		s.handleController(w, req)
+		return
+	case req.URL.Path == peersPath && req.Method == http.MethodGet:
+		s.handlePeers(w, req)
+		return
+	case strings.HasPrefix(req.URL.Path, inputPortsPrefix):
+		// input-ports/{portId}/transactions[/{transactionId}[/flow-files]]
+		parts := strings.Split(strings.TrimPrefix(req.URL.Path, inputPortsPrefix), "/")
+		port, ok := s.ports[parts[0]]
+		if !ok || len(parts) < 2 || parts[1] != "transactions" {
+			break
+		}
+
+		switch {
+		case len(parts) == 2 && req.Method == http.MethodPost:
+			s.handleCreateTransaction(w, req, port)
+			return
+		case len(parts) == 3 && req.Method == http.MethodPut:
+			s.handleExtendTransaction(w, port, parts[2])
+			return
+		case len(parts) == 3 && req.Method == http.MethodDelete:
+			s.handleCommitTransaction(w, req, port, parts[2])
+			return
+		case len(parts) == 4 && parts[3] == "flow-files" && req.Method == http.MethodPost:
+			s.handleReceiveFlowFiles(w, req, port, parts[2])
+			return
+		}
+	}
+
+	http.NotFound(w, req)
+}
+
+func (s *Server) handleController(w http.ResponseWriter, req *http.Request) {
+	_, port, secure := s.peerAddress(req)
+	controller := ControllerDTO{
+		ID:                          s.instanceID,
+		Name:                        s.settings.Name,
+		InputPortCount:              len(s.ports),
+		RemoteSiteHTTPListeningPort: &port,
+		SiteToSiteSecure:            secure,
+		InstanceID:                  s.instanceID,
+		InputPorts:                  make([]PortDTO, 0, len(s.ports)),
+		OutputPorts:                 []PortDTO{},
+	}
+
+	for _, port := range s.ports {
+		controller.InputPorts = append(controller.InputPorts, PortDTO{ID: port.ID, Name: port.Name, State: "RUNNING"})
+	}
+
+	controller.RunningCount = len(controller.InputPorts)
+	writeJSON(w, http.StatusOK, ControllerEntity{Controller: controller})
+}
+
+func (s *Server) handlePeers(w http.ResponseWriter, req *http.Request) {
+	host, port, secure := s.peerAddress(req)
+	writeJSON(w, http.StatusOK, PeersEntity{
+		Peers: []PeerDTO{{Hostname: host, Port: port, Secure: secure}},
+	})
+}
+
+func (s *Server) handleCreateTransaction(w http.ResponseWriter, req *http.Request, port Port) {
+	if strings.EqualFold(req.Header.Get(HeaderUseCompression), "true") {
+		http.Error(w, "Site-to-Site compression is not supported", http.StatusBadRequest)
+		return
+	}
+
+	id := uuid.NewString()
+	s.mu.Lock()
+	s.transactions[id] = &transaction{port: port, expiresAt: time.Now().Add(s.settings.TransactionTTL)}
+	s.mu.Unlock()
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+
+	w.Header().Set("Location", scheme+"://"+req.Host+inputPortsPrefix+port.ID+"/transactions/"+id)
+	w.Header().Set(HeaderLocationURIIntent, LocationURIIntentTxURL)
+	w.Header().Set(HeaderServerSideTTL, strconv.Itoa(int(s.settings.TransactionTTL.Seconds())))
+	writeJSON(w, http.StatusCreated, TransactionResultEntity{ResponseCode: ResponseCodePropertiesOK})
+}
+
+func (s *Server) handleExtendTransaction(w http.ResponseWriter, port Port, id string) {
+	s.mu.Lock()
+	tx, ok := s.transactions[id]
+	if ok && tx.port == port {
+		tx.expiresAt = time.Now().Add(s.settings.TransactionTTL)
+	}
+	s.mu.Unlock()
+
+	if !ok || tx.port != port {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TransactionResultEntity{ResponseCode: ResponseCodeContinueTransaction})
+}
+
+func (s *Server) handleReceiveFlowFiles(w http.ResponseWriter, req *http.Request, port Port, id string) {
+	s.mu.Lock()
+	tx, ok := s.transactions[id]
+	s.mu.Unlock()
+
+	if !ok || tx.port != port {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	var body bytes.Buffer
+	packets, err := DecodePackets(io.TeeReader(req.Body, &body))
+	if err != nil {
+		s.logger.Error("Failed to decode site-to-site packets", zap.String("port", port.Name), zap.Error(err))
+		http.Error(w, "Failed to decode flowfiles", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	tx.packets = append(tx.packets, packets...)
+	tx.checksum = crc32.Update(tx.checksum, crc32.IEEETable, body.Bytes())
+	tx.expiresAt = time.Now().Add(s.settings.TransactionTTL)
+	checksum := tx.checksum
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = io.WriteString(w, strconv.FormatUint(uint64(checksum), 10))
+}
+
+func (s *Server) handleCommitTransaction(w http.ResponseWriter, req *http.Request, port Port, id string) {
+	// the flowfiles and checksum are read under the lock, flowfiles may still be being received
+	s.mu.Lock()
+	tx, ok := s.transactions[id]
+	var packets []DataPacket
+	var txChecksum uint32
+	if ok && tx.port == port {
+		delete(s.transactions, id)
+		packets, txChecksum = tx.packets, tx.checksum
+	}
+	s.mu.Unlock()
+
+	if !ok || tx.port != port {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+
+	code, err := strconv.Atoi(req.URL.Query().Get("responseCode"))
+	if err != nil {
+		http.Error(w, "Invalid responseCode", http.StatusBadRequest)
+		return
+	}
+
+	if checksum := req.URL.Query().Get("checksum"); checksum != "" && checksum != strconv.FormatUint(uint64(txChecksum), 10) {
+		code = int(ResponseCodeBadChecksum)
+	}
+
+	if ResponseCode(code) != ResponseCodeConfirmTransaction {
+		s.logger.Warn("Site-to-site transaction was not confirmed",
+			zap.String("port", port.Name), zap.String("transaction.id", id), zap.Int("response.code", code))
+		writeJSON(w, http.StatusOK, TransactionResultEntity{ResponseCode: ResponseCode(code)})
+		return
+	}
+
+	if err := s.receive(req.Context(), port, packets); err != nil {
+		s.logger.Error("Failed to process site-to-site transaction", zap.String("port", port.Name), zap.Error(err))
+		http.Error(w, "Failed to process flowfiles", http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, TransactionResultEntity{
+		FlowFileSent: len(packets),
+		ResponseCode: ResponseCodeTransactionFinished,
+	})
+}
+
+// peerAddress returns the address clients should use to reach this server
+func (s *Server) peerAddress(req *http.Request) (string, int, bool) {
+	secure := req.TLS != nil
+	address := s.settings.AdvertisedAddress
+	if address == "" {
+		address = req.Host
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		host, portStr = address, "80"
+		if secure {
+			portStr = "443"
+		}
+	}
+
+	port, _ := strconv.Atoi(portStr)
+	return host, port, secure
+}
+
+// expireTransactions discards transactions that were idle for longer than their TTL
+func (s *Server) expireTransactions() {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, tx := range s.transactions {
+		if now.After(tx.expiresAt) {
+			s.logger.Warn("Discarding expired site-to-site transaction", zap.String("transaction.id", id))
+			delete(s.transactions, id)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,134 @@
+package sitetosite
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestDecodePackets(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, EncodePacket(&buf, DataPacket{Attributes: map[string]string{"filename": "a"}, Content: []byte("first")}))
+	buf.Write([]byte{'R', 'C', byte(ResponseCodeContinueTransaction)})
+	require.NoError(t, EncodePacket(&buf, DataPacket{Attributes: map[string]string{}, Content: []byte{}}))
+	buf.Write([]byte{'R', 'C', byte(ResponseCodeFinishTransaction)})
+
+	packets, err := DecodePackets(&buf)
+	require.NoError(t, err)
+	require.Len(t, packets, 2)
+	assert.Equal(t, "a", packets[0].Attributes["filename"])
+	assert.Equal(t, []byte("first"), packets[0].Content)
+	assert.Empty(t, packets[1].Content)
+
+	_, err = DecodePackets(bytes.NewReader([]byte{0, 0, 0, 1, 0, 0}))
+	assert.Error(t, err, "truncated packets should fail to decode")
+}
+
+func TestDecodePacketsAttributeLimits(t *testing.T) {
+	var count bytes.Buffer
+	require.NoError(t, binary.Write(&count, binary.BigEndian, int32(maxAttributes+1)))
+	_, err := DecodePackets(&count)
+	assert.ErrorContains(t, err, "invalid attribute count")
+
+	// the key fits within a single field but the value exceeds what's left of the total
+	var size bytes.Buffer
+	require.NoError(t, binary.Write(&size, binary.BigEndian, int32(1)))
+	require.NoError(t, writeString(&size, "key"))
+	require.NoError(t, binary.Write(&size, binary.BigEndian, int32(maxAttributesLength-2)))
+	_, err = DecodePackets(&size)
+	assert.ErrorContains(t, err, "invalid string length")
+}
+
+func TestServerTransaction(t *testing.T) {
+	var received []DataPacket
+	server := httptest.NewServer(NewServer(zap.NewNop(), Settings{
+		Name:           "test",
+		PortNames:      []string{"provenance"},
+		TransactionTTL: time.Minute,
+	}, func(_ context.Context, port Port, packets []DataPacket) error {
+		assert.Equal(t, "provenance", port.Name)
+		received = append(received, packets...)
+		return nil
+	}))
+	defer server.Close()
+
+	// resolve the port id by name, like a remote process group does
+	resp, err := http.Get(server.URL + "/nifi-api/site-to-site")
+	require.NoError(t, err)
+	var controller ControllerEntity
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&controller))
+	resp.Body.Close()
+	require.Len(t, controller.Controller.InputPorts, 1)
+	assert.Equal(t, SupportedProtocolVersion, resp.Header.Get(HeaderProtocolVersion))
+	portID := controller.Controller.InputPorts[0].ID
+
+	resp, err = http.Get(server.URL + "/nifi-api/site-to-site/peers")
+	require.NoError(t, err)
+	var peers PeersEntity
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&peers))
+	resp.Body.Close()
+	require.Len(t, peers.Peers, 1)
+
+	resp, err = http.Post(server.URL+"/nifi-api/data-transfer/input-ports/"+portID+"/transactions", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, LocationURIIntentTxURL, resp.Header.Get(HeaderLocationURIIntent))
+	txURL := resp.Header.Get("Location")
+
+	var body bytes.Buffer
+	require.NoError(t, EncodePacket(&body, DataPacket{Attributes: map[string]string{"mime.type": "application/json"}, Content: []byte("[]")}))
+	expected := crc32.ChecksumIEEE(body.Bytes())
+
+	resp, err = http.Post(txURL+"/flow-files", "application/octet-stream", &body)
+	require.NoError(t, err)
+	checksum, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, strconv.FormatUint(uint64(expected), 10), string(checksum))
+	assert.Empty(t, received, "packets should not be received before the transaction is confirmed")
+
+	// the checksum covers all the data of the transaction, not only the last request
+	var second bytes.Buffer
+	require.NoError(t, EncodePacket(&second, DataPacket{Attributes: map[string]string{"mime.type": "application/json"}, Content: []byte("[{}]")}))
+	expected = crc32.Update(expected, crc32.IEEETable, second.Bytes())
+
+	resp, err = http.Post(txURL+"/flow-files", "application/octet-stream", &second)
+	require.NoError(t, err)
+	checksum, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, strconv.FormatUint(uint64(expected), 10), string(checksum))
+
+	req, _ := http.NewRequest(http.MethodDelete, txURL+"?responseCode="+strconv.Itoa(int(ResponseCodeConfirmTransaction))+
+		"&checksum="+strconv.FormatUint(uint64(expected), 10), nil)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var result TransactionResultEntity
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	resp.Body.Close()
+
+	assert.Equal(t, ResponseCodeTransactionFinished, result.ResponseCode)
+	assert.Equal(t, 2, result.FlowFileSent)
+	require.Len(t, received, 2)
+	assert.Equal(t, []byte("[]"), received[0].Content)
+	assert.Equal(t, []byte("[{}]"), received[1].Content)
+
+	// the transaction is gone once committed
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
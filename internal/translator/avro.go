@@ -0,0 +1,237 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// DecodeAvroProvenanceEvents decodes an Avro object container file of provenance events,
+// records are mapped using the same field names as the JSON encoding
+func DecodeAvroProvenanceEvents(r io.Reader) ([]ProvenanceEvent, error) {
+	return decodeAvro[ProvenanceEvent](r)
+}
+
+// StreamAvroProvenanceEvents decodes an Avro object container file of provenance events
+// record by record, fn is called with each event and decoding stops at the first error
+func StreamAvroProvenanceEvents(r io.Reader, fn func(ProvenanceEvent) error) error {
+	return streamAvro(r, fn)
+}
+
+// DecodeAvroBulletinEvents decodes an Avro object container file of bulletin events,
+// records are mapped using the same field names as the JSON encoding
+func DecodeAvroBulletinEvents(r io.Reader) ([]BulletinEvent, error) {
+	return decodeAvro[BulletinEvent](r)
+}
+
+// decodeAvro decodes every record of the container using the schema embedded in it
+func decodeAvro[T any](r io.Reader) ([]T, error) {
+	var results []T
+	err := streamAvro(r, func(result T) error {
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// streamAvro decodes the records of the container one at a time using the schema embedded in it
+func streamAvro[T any](r io.Reader, fn func(T) error) error {
+	ocf, err := goavro.NewOCFReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read avro container: %w", err)
+	}
+
+	var schema any
+	if err := json.Unmarshal([]byte(ocf.Codec().Schema()), &schema); err != nil {
+		return fmt.Errorf("failed to parse avro schema: %w", err)
+	}
+
+	names := make(map[string]any)
+	collectNamedSchemas(schema, "", names)
+
+	for ocf.Scan() {
+		datum, err := ocf.Read()
+		if err != nil {
+			return fmt.Errorf("failed to read avro record: %w", err)
+		}
+
+		// Round trip through JSON so the struct tags define the mapping
+		data, err := json.Marshal(normalizeAvro(schema, datum, names))
+		if err != nil {
+			return fmt.Errorf("failed to encode avro record: %w", err)
+		}
+
+		var result T
+		if err := json.Unmarshal(data, &result); err != nil {
+			return fmt.Errorf("failed to map avro record: %w", err)
+		}
+
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+
+	if err := ocf.Err(); err != nil {
+		return fmt.Errorf("failed to read avro container: %w", err)
+	}
+	return nil
+}
+
+// collectNamedSchemas indexes the named types (records, enums and fixed) of a schema by
+// name and full name, goavro uses the full name as the key of union values
+func collectNamedSchemas(schema any, namespace string, names map[string]any) {
+	switch s := schema.(type) {
+	case []any:
+		for _, branch := range s {
+			collectNamedSchemas(branch, namespace, names)
+		}
+	case map[string]any:
+		if ns, ok := s["namespace"].(string); ok {
+			namespace = ns
+		}
+
+		if name, ok := s["name"].(string); ok {
+			fullName := name
+			if !strings.Contains(name, ".") && namespace != "" {
+				fullName = namespace + "." + name
+			}
+			names[name] = s
+			names[fullName] = s
+		}
+
+		switch s["type"] {
+		case "record":
+			fields, _ := s["fields"].([]any)
+			for _, field := range fields {
+				if f, ok := field.(map[string]any); ok {
+					collectNamedSchemas(f["type"], namespace, names)
+				}
+			}
+		case "array":
+			collectNamedSchemas(s["items"], namespace, names)
+		case "map":
+			collectNamedSchemas(s["values"], namespace, names)
+		}
+	}
+}
+
+// normalizeAvro converts goavro's native representation into plain JSON values,
+// unions are unwrapped and logical types are converted to their JSON equivalent
+func normalizeAvro(schema any, value any, names map[string]any) any {
+	if value == nil {
+		return nil
+	}
+
+	switch s := schema.(type) {
+	case string:
+		if named, ok := names[s]; ok {
+			return normalizeAvro(named, value, names)
+		}
+		return normalizeAvroPrimitive(value)
+	case []any:
+		union, ok := value.(map[string]any)
+		if !ok {
+			return normalizeAvroPrimitive(value)
+		}
+
+		for typeName, v := range union {
+			if branch := findUnionBranch(s, typeName); branch != nil {
+				return normalizeAvro(branch, v, names)
+			}
+			return normalizeAvroPrimitive(v)
+		}
+		return nil
+	case map[string]any:
+		switch s["type"] {
+		case "record":
+			record, ok := value.(map[string]any)
+			if !ok {
+				return value
+			}
+
+			fields, _ := s["fields"].([]any)
+			result := make(map[string]any, len(record))
+			for _, field := range fields {
+				f, ok := field.(map[string]any)
+				if !ok {
+					continue
+				}
+				name, _ := f["name"].(string)
+				result[name] = normalizeAvro(f["type"], record[name], names)
+			}
+			return result
+		case "array":
+			items, ok := value.([]any)
+			if !ok {
+				return value
+			}
+
+			result := make([]any, len(items))
+			for i, item := range items {
+				result[i] = normalizeAvro(s["items"], item, names)
+			}
+			return result
+		case "map":
+			entries, ok := value.(map[string]any)
+			if !ok {
+				return value
+			}
+
+			result := make(map[string]any, len(entries))
+			for k, v := range entries {
+				result[k] = normalizeAvro(s["values"], v, names)
+			}
+			return result
+		default:
+			return normalizeAvroPrimitive(value)
+		}
+	default:
+		return normalizeAvroPrimitive(value)
+	}
+}
+
+// normalizeAvroPrimitive converts logical type values to their underlying JSON value
+func normalizeAvroPrimitive(value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UnixMilli()
+	case time.Duration:
+		return v.Milliseconds()
+	case []byte:
+		return string(v)
+	default:
+		return v
+	}
+}
+
+// findUnionBranch returns the union branch matching the type name used by goavro,
+// named types are keyed by their full name while the schema may use the short name
+func findUnionBranch(union []any, typeName string) any {
+	for _, branch := range union {
+		name := avroTypeName(branch)
+		if name == typeName || strings.HasSuffix(typeName, "."+name) {
+			return branch
+		}
+	}
+	return nil
+}
+
+// avroTypeName returns the name of a union branch
+func avroTypeName(schema any) string {
+	switch s := schema.(type) {
+	case string:
+		return s
+	case map[string]any:
+		if name, ok := s["name"].(string); ok {
+			return name
+		}
+		if t, ok := s["type"].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package translator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const provenanceAvroSchema = `{
+  "namespace": "nifi",
+  "name": "provenanceEvent",
+  "type": "record",
+  "fields": [
+    { "name": "eventId", "type": "string" },
+    { "name": "eventOrdinal", "type": "long" },
+    { "name": "eventType", "type": "string" },
+    { "name": "timestampMillis", "type": { "type": "long", "logicalType": "timestamp-millis" } },
+    { "name": "durationMillis", "type": "long" },
+    { "name": "componentName", "type": ["null", "string"] },
+    { "name": "entitySize", "type": ["null", "long"] },
+    { "name": "updatedAttributes", "type": { "type": "map", "values": "string" } },
+    { "name": "childIds", "type": ["null", { "type": "array", "items": "string" }] }
+  ]
+}`
+
+func TestDecodeAvroProvenanceEvents(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Schema: provenanceAvroSchema})
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Append([]map[string]any{
+		{
+			"eventId":           "1f3c9a54-0c52-4d3e-9d4a-6f6d7b1c2e11",
+			"eventOrdinal":      int64(42),
+			"eventType":         "FORK",
+			"timestampMillis":   time.UnixMilli(1700000000123),
+			"durationMillis":    int64(7),
+			"componentName":     goavro.Union("string", "SplitText"),
+			"entitySize":        nil,
+			"updatedAttributes": map[string]any{"filename": "a.txt"},
+			"childIds":          goavro.Union("array", []any{"child-1", "child-2"}),
+		},
+	}))
+
+	events, err := DecodeAvroProvenanceEvents(&buf)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	event := events[0]
+	assert.Equal(t, "1f3c9a54-0c52-4d3e-9d4a-6f6d7b1c2e11", event.EventId)
+	assert.Equal(t, int64(42), event.EventOrdinal)
+	assert.Equal(t, ProvenanceEventTypeFork, event.EventType)
+	assert.Equal(t, int64(1700000000123), event.TimestampMillis)
+	assert.Equal(t, int64(7), event.DurationMillis)
+	assert.Equal(t, "SplitText", event.ComponentName)
+	assert.Zero(t, event.EntitySize)
+	assert.Equal(t, map[string]string{"filename": "a.txt"}, event.UpdatedAttributes)
+	assert.Equal(t, []string{"child-1", "child-2"}, event.ChildIds)
+}
+
+// bulletinAvroSchema follows the schema of the SiteToSiteBulletinReportingTask, whose fields are nullable
+const bulletinAvroSchema = `{
+  "namespace": "nifi",
+  "name": "bulletinEvent",
+  "type": "record",
+  "fields": [
+    { "name": "objectId", "type": "string" },
+    { "name": "platform", "type": "string" },
+    { "name": "bulletinId", "type": "long" },
+    { "name": "bulletinCategory", "type": ["null", "string"] },
+    { "name": "bulletinGroupId", "type": ["null", "string"] },
+    { "name": "bulletinGroupName", "type": ["null", "string"] },
+    { "name": "bulletinGroupPath", "type": ["null", "string"] },
+    { "name": "bulletinLevel", "type": ["null", "string"] },
+    { "name": "bulletinMessage", "type": ["null", "string"] },
+    { "name": "bulletinNodeAddress", "type": ["null", "string"] },
+    { "name": "bulletinNodeId", "type": ["null", "string"] },
+    { "name": "bulletinSourceId", "type": ["null", "string"] },
+    { "name": "bulletinSourceName", "type": ["null", "string"] },
+    { "name": "bulletinSourceType", "type": ["null", "string"] },
+    { "name": "bulletinTimestamp", "type": ["null", "string"] },
+    { "name": "bulletinFlowFileUuid", "type": ["null", "string"] }
+  ]
+}`
+
+func TestDecodeAvroBulletinEvents(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := goavro.NewOCFWriter(goavro.OCFConfig{W: &buf, Schema: bulletinAvroSchema})
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Append([]map[string]any{
+		{
+			"objectId":             "7d9e2f40-3c1b-4a5e-8f6d-1b2c3d4e5f60",
+			"platform":             "nifi",
+			"bulletinId":           int64(12),
+			"bulletinCategory":     goavro.Union("string", "Log Message"),
+			"bulletinGroupId":      goavro.Union("string", "0b6e3f54-018e-1000-6a1d-3c25f2a11e0a"),
+			"bulletinGroupName":    goavro.Union("string", "orders"),
+			"bulletinGroupPath":    goavro.Union("string", "NiFi Flow / orders"),
+			"bulletinLevel":        goavro.Union("string", "ERROR"),
+			"bulletinMessage":      goavro.Union("string", "PublishKafka failed to send"),
+			"bulletinNodeAddress":  goavro.Union("string", "nifi-0.nifi.svc"),
+			"bulletinNodeId":       nil,
+			"bulletinSourceId":     goavro.Union("string", "1c2d3e4f-018e-1000-7b2e-4d36a3b22f1b"),
+			"bulletinSourceName":   goavro.Union("string", "PublishKafka"),
+			"bulletinSourceType":   goavro.Union("string", "PROCESSOR"),
+			"bulletinTimestamp":    goavro.Union("string", "2024-04-05T10:00:01.500Z"),
+			"bulletinFlowFileUuid": goavro.Union("string", "1f3c9a54-0c52-4d3e-9d4a-6f6d7b1c2e11"),
+		},
+	}))
+
+	events, err := DecodeAvroBulletinEvents(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []BulletinEvent{{
+		ObjectId:             "7d9e2f40-3c1b-4a5e-8f6d-1b2c3d4e5f60",
+		Platform:             "nifi",
+		BulletinId:           12,
+		BulletinCategory:     "Log Message",
+		BulletinGroupId:      "0b6e3f54-018e-1000-6a1d-3c25f2a11e0a",
+		BulletinGroupName:    "orders",
+		BulletinGroupPath:    "NiFi Flow / orders",
+		BulletinLevel:        "ERROR",
+		BulletinMessage:      "PublishKafka failed to send",
+		BulletinNodeAddress:  "nifi-0.nifi.svc",
+		BulletinSourceId:     "1c2d3e4f-018e-1000-7b2e-4d36a3b22f1b",
+		BulletinSourceName:   "PublishKafka",
+		BulletinSourceType:   "PROCESSOR",
+		BulletinTimestamp:    "2024-04-05T10:00:01.500Z",
+		BulletinFlowFileUuid: "1f3c9a54-0c52-4d3e-9d4a-6f6d7b1c2e11",
+	}}, events)
+}
+
+func TestDecodeAvroInvalidContainer(t *testing.T) {
+	_, err := DecodeAvroBulletinEvents(bytes.NewReader([]byte("[]")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// The names of the span events bulletins are recorded as
+const (
+	bulletinSpanEvent  = "bulletin"
+	exceptionSpanEvent = "exception"
+)
+
+// bulletinSpan returns the span of the component that reported the bulletin while handling
+// its flowfile, false when the component's span wasn't translated yet
+func (t *eventTranslator) bulletinSpan(event BulletinEvent) (trace.SpanContext, bool) {
+	entry, ok := t.spanContextTracking.Get(event.BulletinFlowFileUuid)
+	if !ok {
+		return trace.SpanContext{}, false
+	}
+
+	spanCtx, ok := entry.ComponentSpans[event.BulletinSourceId]
+	return spanCtx, ok && spanCtx.IsValid()
+}
+
+// appendBulletinEvent records the bulletin as a span event, ERROR bulletins and bulletins
+// embedding a Java exception are recorded as an exception event, ERROR bulletins fail the span
+func (t *eventTranslator) appendBulletinEvent(span ptrace.Span, event BulletinEvent, timestamp pcommon.Timestamp) {
+	message := t.attributeFilter.redactValue(redactionKeyBulletinMessage, event.BulletinMessage)
+	exception, parsed := parseJavaException(message)
+	isError := strings.EqualFold(event.BulletinLevel, "error")
+
+	spanEvent := span.Events().AppendEmpty()
+	spanEvent.SetTimestamp(timestamp)
+	spanEvent.SetName(bulletinSpanEvent)
+	if isError || parsed {
+		spanEvent.SetName(exceptionSpanEvent)
+		putExceptionAttributes(spanEvent.Attributes(), exception, message)
+	}
+
+	if isError {
+		span.Status().SetCode(ptrace.StatusCodeError)
+		span.Status().SetMessage(message)
+	}
+
+	t.putBulletinAttributes(spanEvent.Attributes(), event)
+}
+
+// appendBulletinSpan appends a span for a bulletin that couldn't be recorded on the span of its
+// component, a child of that span when it was exported by an earlier batch or of its flowfile's span otherwise
+func (t *eventTranslator) appendBulletinSpan(slice ptrace.SpanSlice, event BulletinEvent) {
+	defaultSpanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID(uuidToTraceID(event.BulletinFlowFileUuid)),
+	})
+
+	ctx, ok := t.spanContextTracking.Get(event.BulletinFlowFileUuid)
+	if !ok {
+		ctx.SpanContext = defaultSpanCtx
+	}
+
+	// spans can't be changed once exported, the bulletin is recorded next to the span of its component
+	if componentSpan, ok := t.bulletinSpan(event); ok {
+		ctx.SpanContext = componentSpan
+	}
+
+	newSpan := slice.AppendEmpty()
+	newSpan.SetKind(ptrace.SpanKindInternal)
+	newSpan.SetSpanID(uuidToSpanID(event.ObjectId))
+	newSpan.SetTraceID(pcommon.TraceID(ctx.SpanContext.TraceID()))
+	newSpan.SetParentSpanID(pcommon.SpanID(ctx.SpanContext.SpanID()))
+
+	switch strings.ToLower(event.BulletinLevel) {
+	case "error":
+		newSpan.Status().SetCode(ptrace.StatusCodeError)
+		newSpan.Status().SetMessage(t.attributeFilter.redactValue(redactionKeyBulletinMessage, event.BulletinMessage))
+	default:
+		newSpan.Status().SetCode(ptrace.StatusCodeUnset)
+	}
+
+	newSpan.SetName(fmt.Sprintf("%s %s", event.BulletinSourceName, event.BulletinLevel))
+
+	ts, err := time.Parse("2006-01-02T15:04:05.999Z", event.BulletinTimestamp)
+	if err != nil {
+		t.logger.Error("failed to parse timestamp for event",
+			zap.String("object.id", event.ObjectId),
+			zap.Int64("bulletin.id", event.BulletinId))
+		return
+	}
+
+	newSpan.SetStartTimestamp(pcommon.Timestamp(ts.UnixMilli() * 1000000))
+	newSpan.SetEndTimestamp(
+		pcommon.Timestamp(ts.UnixMilli() * 1000000),
+	) // Bulletin events dont have any duration
+
+	t.appendExceptionEvent(newSpan, event)
+	t.putBulletinAttributes(newSpan.Attributes(), event)
+}
+
+// appendExceptionEvent records the Java exception embedded in the bulletin message, if any,
+// as an exception event of the bulletin's own span
+func (t *eventTranslator) appendExceptionEvent(span ptrace.Span, event BulletinEvent) {
+	message := t.attributeFilter.redactValue(redactionKeyBulletinMessage, event.BulletinMessage)
+	exception, ok := parseJavaException(message)
+	if !ok {
+		return
+	}
+
+	spanEvent := span.Events().AppendEmpty()
+	spanEvent.SetTimestamp(span.EndTimestamp())
+	spanEvent.SetName(exceptionSpanEvent)
+	putExceptionAttributes(spanEvent.Attributes(), exception, message)
+}
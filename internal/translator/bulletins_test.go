@@ -0,0 +1,129 @@
+package translator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// newTestBulletin returns a bulletin of the PublishKafka processor of the synthetic_split.json lineage
+func newTestBulletin(objectID, flowFileID, level string) BulletinEvent {
+	return BulletinEvent{
+		ObjectId:             objectID,
+		Platform:             "nifi",
+		BulletinId:           311,
+		BulletinCategory:     "Log Message",
+		BulletinGroupId:      "0b6e2d11-018e-1000-2f4c-95a0b3e2c7d4",
+		BulletinGroupName:    "orders",
+		BulletinGroupPath:    "NiFi Flow / orders",
+		BulletinLevel:        level,
+		BulletinMessage:      "PublishKafka_2_6[id=0b6e5c9e-018e-1000-4e7a-d2f1c8b03e55] Failed to send FlowFile to Kafka",
+		BulletinNodeAddress:  "nifi-0.nifi.svc",
+		BulletinSourceId:     "0b6e5c9e-018e-1000-4e7a-d2f1c8b03e55",
+		BulletinSourceName:   "Publish orders",
+		BulletinSourceType:   "PROCESSOR",
+		BulletinTimestamp:    "2024-04-05T10:20:00.700Z",
+		BulletinFlowFileUuid: flowFileID,
+	}
+}
+
+// bulletinSpans indexes the spans of the traces by their nifi.object.id attribute
+func bulletinSpans(traces ptrace.Traces) map[string]ptrace.Span {
+	spans := make(map[string]ptrace.Span)
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		scopeSpans := traces.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			for k := 0; k < scopeSpans.At(j).Spans().Len(); k++ {
+				span := scopeSpans.At(j).Spans().At(k)
+				if id, ok := span.Attributes().Get("nifi.object.id"); ok {
+					spans[id.Str()] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func TestTranslateBulletinEventsMatchComponentSpan(t *testing.T) {
+	const (
+		receive   = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a01"
+		send      = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a04"
+		child     = "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01"
+		parent    = "a3f0c5d2-7e1b-4f6a-8c2d-1b9e4d7f3a10"
+		unknown   = "d4c3b2a1-0f9e-4d8c-b7a6-958473625140"
+		matched   = "7a0f1c2e-0000-4000-8000-000000000001"
+		warning   = "7a0f1c2e-0000-4000-8000-000000000002"
+		otherComp = "7a0f1c2e-0000-4000-8000-000000000003"
+		early     = "7a0f1c2e-0000-4000-8000-000000000004"
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{BulletinHoldTimeout: time.Minute, BulletinHoldMaxEntries: 10})
+
+	// the child flowfile is known once forked, but hasn't reached PublishKafka yet
+	lineage := loadLineage(t, "synthetic_split.json")
+	spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(lineage[:3])))
+
+	bulletins := []BulletinEvent{
+		newTestBulletin(matched, child, "ERROR"),
+		newTestBulletin(warning, child, "WARNING"),
+		// the parent flowfile never went through PublishKafka
+		newTestBulletin(otherComp, parent, "ERROR"),
+		newTestBulletin(early, unknown, "ERROR"),
+	}
+	assert.Equal(t, 0, et.TranslateBulletinEvents(bulletins).SpanCount())
+
+	// the bulletins are recorded on the provenance span of the component that reported them
+	traces := tracesOf(et.TranslateProvenanceEvents(lineage[3:]))
+	span := spansByEventID(traces)[send]
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	assert.Equal(t, bulletins[0].BulletinMessage, span.Status().Message())
+	require.Equal(t, 2, span.Events().Len())
+	assert.Equal(t, "exception", span.Events().At(0).Name())
+	assert.Equal(t, "2024-04-05T10:20:00.7Z", span.Events().At(0).Timestamp().AsTime().Format("2006-01-02T15:04:05.999Z"))
+	message, ok := span.Events().At(0).Attributes().Get("exception.message")
+	require.True(t, ok)
+	assert.Equal(t, bulletins[0].BulletinMessage, message.Str())
+	assert.Equal(t, "bulletin", span.Events().At(1).Name())
+	assert.Empty(t, bulletinSpans(traces), "matched bulletins don't get a span of their own")
+
+	// without a span of their component, the bulletins fall back to a span of their flowfile
+	result := bulletinSpans(tracesOf(et.ReleaseExpiredBulletins(time.Now().Add(2 * time.Minute))))
+	require.Len(t, result, 2)
+
+	span = result[otherComp]
+	assert.Equal(t, spans[receive].TraceID(), span.TraceID())
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	assert.Equal(t, 0, span.Events().Len())
+
+	span = result[early]
+	assert.Equal(t, uuidToTraceID(unknown), span.TraceID())
+	assert.True(t, span.ParentSpanID().IsEmpty())
+}
+
+func TestTranslateBulletinEventsExportedComponentSpan(t *testing.T) {
+	const (
+		send    = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a04"
+		child   = "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01"
+		matched = "7a0f1c2e-0000-4000-8000-000000000001"
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{BulletinHoldTimeout: time.Minute, BulletinHoldMaxEntries: 10})
+	spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_split.json"))))
+
+	// the span of the component was already exported, the bulletin becomes its child
+	bulletins := []BulletinEvent{newTestBulletin(matched, child, "ERROR")}
+	span := bulletinSpans(et.TranslateBulletinEvents(bulletins))[matched]
+	assert.Equal(t, spans[send].TraceID(), span.TraceID())
+	assert.Equal(t, spans[send].SpanID(), span.ParentSpanID())
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+
+	// logs refer to the span of the component as well
+	logs := et.TranslateBulletinEventsToLogs(bulletins)
+	record := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, spans[send].TraceID(), record.TraceID())
+	assert.Equal(t, spans[send].SpanID(), record.SpanID())
+}
@@ -0,0 +1,79 @@
+package translator
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// DeduplicateProvenanceEvents drops the events already seen by the signal's pipeline within the
+// deduplication TTL, the remaining events are marked as seen
+func (t *eventTranslator) DeduplicateProvenanceEvents(signal component.DataType, events []ProvenanceEvent) []ProvenanceEvent {
+	seen := t.seenProvenance[signal]
+	if seen == nil {
+		return events
+	}
+
+	unique := make([]ProvenanceEvent, 0, len(events))
+	for _, event := range events {
+		if event.EventId == "" || seen.add(event.EventId) {
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+// DeduplicateBulletinEvents drops the bulletins already seen by the signal's pipeline within the
+// deduplication TTL, the remaining bulletins are marked as seen
+func (t *eventTranslator) DeduplicateBulletinEvents(signal component.DataType, events []BulletinEvent) []BulletinEvent {
+	seen := t.seenBulletins[signal]
+	if seen == nil {
+		return events
+	}
+
+	unique := make([]BulletinEvent, 0, len(events))
+	for _, event := range events {
+		if seen.add(bulletinKey(event)) {
+			unique = append(unique, event)
+		}
+	}
+	return unique
+}
+
+// ForgetProvenanceEvents forgets the events were seen by the signal's pipeline, so they are
+// accepted when redelivered
+func (t *eventTranslator) ForgetProvenanceEvents(signal component.DataType, events []ProvenanceEvent) {
+	seen := t.seenProvenance[signal]
+	if seen == nil {
+		return
+	}
+
+	for _, event := range events {
+		seen.forget(event.EventId)
+	}
+}
+
+// ForgetBulletinEvents forgets the bulletins were seen by the signal's pipeline, so they are
+// accepted when redelivered. Held bulletins are kept as seen, the hold still owns them
+func (t *eventTranslator) ForgetBulletinEvents(signal component.DataType, events []BulletinEvent) {
+	seen := t.seenBulletins[signal]
+	if seen == nil {
+		return
+	}
+
+	for _, event := range events {
+		if signal == component.DataTypeTraces && t.bulletinHold != nil && t.bulletinHold.contains(event) {
+			continue
+		}
+		seen.forget(bulletinKey(event))
+	}
+}
+
+// bulletinKey identifies a bulletin, bulletin ids are only unique within a node
+func bulletinKey(event BulletinEvent) string {
+	node := event.BulletinNodeId
+	if node == "" {
+		node = event.BulletinNodeAddress
+	}
+	return fmt.Sprintf("%s/%d", node, event.BulletinId)
+}
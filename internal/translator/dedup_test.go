@@ -0,0 +1,52 @@
+package translator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/zap"
+)
+
+func TestSeenSet(t *testing.T) {
+	now := time.Now()
+	seen := newSeenSet(time.Minute, 2)
+	seen.now = func() time.Time { return now }
+
+	assert.True(t, seen.add("a"))
+	assert.False(t, seen.add("a"))
+	assert.True(t, seen.add("b"))
+
+	// the set is full, the oldest key is evicted
+	assert.True(t, seen.add("c"))
+	assert.True(t, seen.add("a"))
+
+	seen.forget("c")
+	assert.True(t, seen.add("c"))
+
+	now = now.Add(2 * time.Minute)
+	assert.True(t, seen.add("a"), "expired keys should be accepted again")
+}
+
+func TestDeduplicateEvents(t *testing.T) {
+	et := NewEventTranslator(zap.NewNop(), Settings{DeduplicationTTL: time.Minute, DeduplicationMaxEntries: 100})
+
+	events := []ProvenanceEvent{{EventId: "1"}, {EventId: "2"}, {EventId: "1"}}
+	assert.Len(t, et.DeduplicateProvenanceEvents(component.DataTypeTraces, events), 2)
+	assert.Empty(t, et.DeduplicateProvenanceEvents(component.DataTypeTraces, events))
+
+	et.ForgetProvenanceEvents(component.DataTypeTraces, events[:1])
+	assert.Len(t, et.DeduplicateProvenanceEvents(component.DataTypeTraces, events), 1)
+
+	// each signal's pipeline sees the events on its own
+	assert.Len(t, et.DeduplicateProvenanceEvents(component.DataTypeMetrics, events), 2)
+
+	// bulletin ids are only unique within a node
+	bulletins := []BulletinEvent{
+		{BulletinId: 1, BulletinNodeId: "node-1"},
+		{BulletinId: 1, BulletinNodeId: "node-2"},
+		{BulletinId: 1, BulletinNodeId: "node-1"},
+	}
+	assert.Len(t, et.DeduplicateBulletinEvents(component.DataTypeLogs, bulletins), 2)
+}
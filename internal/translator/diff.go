@@ -0,0 +1,72 @@
+package translator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// attributesChangedEvent is the name of the span event recording the attribute changes of an event
+const attributesChangedEvent = "attributes.changed"
+
+// appendAttributeChanges records the flowfile attributes the event added, changed or removed
+// as an attributes.changed span event. NiFi reports the attributes before the event and only
+// the attributes updated by it, removed attributes are reported empty
+func (t *eventTranslator) appendAttributeChanges(span ptrace.Span, event ProvenanceEvent) {
+	keys := make([]string, 0, len(event.UpdatedAttributes))
+	for key := range event.UpdatedAttributes {
+		if t.attributeFilter.keep(key) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var added, changed, removed []string
+	attrs := pcommon.NewMap()
+	for _, key := range keys {
+		updated := event.UpdatedAttributes[key]
+		previous, existed := event.PreviousAttributes[key]
+		name := fmt.Sprintf("nifi.attributes.%s", strings.ToLower(key))
+
+		switch {
+		case !existed && updated != "":
+			added = append(added, key)
+			attrs.PutStr(name+".new", t.attributeFilter.redactValue(key, updated))
+		case existed && updated == "" && previous != "":
+			removed = append(removed, key)
+			attrs.PutStr(name+".old", t.attributeFilter.redactValue(key, previous))
+		case existed && updated != previous:
+			changed = append(changed, key)
+			attrs.PutStr(name+".old", t.attributeFilter.redactValue(key, previous))
+			attrs.PutStr(name+".new", t.attributeFilter.redactValue(key, updated))
+		}
+	}
+
+	if len(added)+len(changed)+len(removed) == 0 {
+		return
+	}
+
+	putKeys(attrs, "nifi.attributes.added", added)
+	putKeys(attrs, "nifi.attributes.changed", changed)
+	putKeys(attrs, "nifi.attributes.removed", removed)
+
+	spanEvent := span.Events().AppendEmpty()
+	spanEvent.SetName(attributesChangedEvent)
+	spanEvent.SetTimestamp(span.EndTimestamp())
+	attrs.CopyTo(spanEvent.Attributes())
+}
+
+// putKeys sets the attribute names as a slice, unless empty
+func putKeys(attrs pcommon.Map, name string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	slice := attrs.PutEmptySlice(name)
+	for _, key := range keys {
+		slice.AppendEmpty().SetStr(key)
+	}
+}
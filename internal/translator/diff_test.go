@@ -0,0 +1,81 @@
+package translator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTranslateProvenanceEventsAttributeChanges(t *testing.T) {
+	filter, err := NewAttributeFilter(AttributeFilterSettings{
+		Exclude: []string{"http.headers.*"},
+		Redact:  []RedactionRule{{Keys: []string{"customer.email"}, Action: RedactionMask}},
+	})
+	require.NoError(t, err)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{
+		Attributes:             filter,
+		RecordAttributeChanges: true,
+		OmitSpanAttributes:     true,
+	})
+
+	events := []ProvenanceEvent{
+		{
+			EventId:         "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b10",
+			EventType:       ProvenanceEventTypeAttributesModified,
+			TimestampMillis: 1712313000520,
+			EntityId:        "e5a7b9c1-3d4f-4a6b-8c0d-2e4f6a8b0c01",
+			PreviousAttributes: map[string]string{
+				"filename":                   "readings.json",
+				"mime.type":                  "text/plain",
+				"customer.email":             "jane.doe@example.com",
+				"retry.count":                "2",
+				"http.headers.Authorization": "Bearer abc",
+			},
+			UpdatedAttributes: map[string]string{
+				"mime.type":                  "application/json",
+				"customer.email":             "john.doe@example.com",
+				"sensor.id":                  "s-17",
+				"retry.count":                "",
+				"http.headers.Authorization": "",
+			},
+		},
+		{
+			EventId:            "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b11",
+			EventType:          ProvenanceEventTypeAttributesModified,
+			TimestampMillis:    1712313000530,
+			EntityId:           "e5a7b9c1-3d4f-4a6b-8c0d-2e4f6a8b0c01",
+			PreviousAttributes: map[string]string{"sensor.id": "s-17"},
+			UpdatedAttributes:  map[string]string{"sensor.id": "s-17"},
+		},
+	}
+
+	spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(events)))
+	span := spans["2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b10"]
+
+	// the full attribute set stays off the span
+	for key := range span.Attributes().AsRaw() {
+		assert.NotContains(t, key, "nifi.attributes.")
+	}
+
+	require.Equal(t, 1, span.Events().Len())
+	event := span.Events().At(0)
+	assert.Equal(t, "attributes.changed", event.Name())
+	assert.Equal(t, span.EndTimestamp(), event.Timestamp())
+	assert.Equal(t, map[string]any{
+		"nifi.attributes.added":              []any{"sensor.id"},
+		"nifi.attributes.changed":            []any{"customer.email", "mime.type"},
+		"nifi.attributes.removed":            []any{"retry.count"},
+		"nifi.attributes.sensor.id.new":      "s-17",
+		"nifi.attributes.customer.email.old": "****",
+		"nifi.attributes.customer.email.new": "****",
+		"nifi.attributes.mime.type.old":      "text/plain",
+		"nifi.attributes.mime.type.new":      "application/json",
+		"nifi.attributes.retry.count.old":    "2",
+	}, event.Attributes().AsRaw())
+
+	// nothing changed
+	assert.Equal(t, 0, spans["2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b11"].Events().Len())
+}
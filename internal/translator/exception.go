@@ -0,0 +1,69 @@
+package translator
+
+import (
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// javaExceptionPattern matches a fully qualified exception class, e.g. java.io.IOException,
+// optionally followed by its message
+var javaExceptionPattern = regexp.MustCompile(`(?:^|[^\w.$])((?:[a-zA-Z_$][\w$]*\.)+[A-Z][\w$]*(?:Exception|Error|Throwable))(?::[ \t]*([^\r\n]*))?`)
+
+// javaException is an exception parsed from a bulletin message
+type javaException struct {
+	Type       string
+	Message    string
+	Stacktrace string
+}
+
+// parseJavaException parses the first exception of a bulletin message, e.g.
+// "PutSFTP[id=...] Failed to transfer: java.io.IOException: Connection reset", the stack
+// trace is kept when the message embeds one, with its cause chain
+func parseJavaException(message string) (javaException, bool) {
+	match := javaExceptionPattern.FindStringSubmatchIndex(message)
+	if match == nil {
+		return javaException{}, false
+	}
+
+	exception := javaException{Type: message[match[2]:match[3]]}
+	if match[4] >= 0 {
+		exception.Message = strings.TrimSpace(message[match[4]:match[5]])
+	}
+
+	if hasStacktrace(message[match[3]:]) {
+		exception.Stacktrace = strings.TrimSpace(message[match[2]:])
+	}
+	return exception, true
+}
+
+// hasStacktrace returns true if the lines following the exception are stack frames or causes
+func hasStacktrace(text string) bool {
+	lines := strings.Split(text, "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "at ") || strings.HasPrefix(line, "Caused by:") {
+			return true
+		}
+	}
+	return false
+}
+
+// putExceptionAttributes sets the exception semantic convention attributes, the message
+// falls back to the whole bulletin message
+func putExceptionAttributes(attrs pcommon.Map, exception javaException, message string) {
+	if exception.Type != "" {
+		attrs.PutStr(string(semconv.ExceptionTypeKey), exception.Type)
+	}
+
+	if exception.Message != "" {
+		message = exception.Message
+	}
+	attrs.PutStr(string(semconv.ExceptionMessageKey), message)
+
+	if exception.Stacktrace != "" {
+		attrs.PutStr(string(semconv.ExceptionStacktraceKey), exception.Stacktrace)
+	}
+}
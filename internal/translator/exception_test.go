@@ -0,0 +1,123 @@
+package translator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseJavaException(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected javaException
+		ok       bool
+	}{
+		{
+			name:     "no exception",
+			message:  "PublishKafka_2_6[id=0b6e5c9e-018e-1000-4e7a-d2f1c8b03e55] Failed to send FlowFile to Kafka",
+			expected: javaException{},
+		},
+		{
+			name:    "exception with message",
+			message: "PutSFTP[id=0b6e5c9e-018e-1000-4e7a-d2f1c8b03e55] Failed to transfer file: java.io.IOException: Connection reset",
+			expected: javaException{
+				Type:    "java.io.IOException",
+				Message: "Connection reset",
+			},
+			ok: true,
+		},
+		{
+			name:    "cause chain keeps the outermost exception",
+			message: "PutDatabaseRecord[id=1] Failed to put records due to org.apache.nifi.processor.exception.ProcessException: java.sql.SQLException: Connection is closed",
+			expected: javaException{
+				Type:    "org.apache.nifi.processor.exception.ProcessException",
+				Message: "java.sql.SQLException: Connection is closed",
+			},
+			ok: true,
+		},
+		{
+			name:     "exception without message",
+			message:  "InvokeHTTP[id=1] Routing to Failure due to java.net.SocketTimeoutException",
+			expected: javaException{Type: "java.net.SocketTimeoutException"},
+			ok:       true,
+		},
+		{
+			name: "stack trace",
+			message: "ExecuteScript[id=1] Failed to process session: java.lang.IllegalStateException: boom\n" +
+				"\tat org.example.Script.run(Script.java:12)\n" +
+				"Caused by: java.lang.NullPointerException: null\n" +
+				"\tat org.example.Script.load(Script.java:4)",
+			expected: javaException{
+				Type:    "java.lang.IllegalStateException",
+				Message: "boom",
+				Stacktrace: "java.lang.IllegalStateException: boom\n" +
+					"\tat org.example.Script.run(Script.java:12)\n" +
+					"Caused by: java.lang.NullPointerException: null\n" +
+					"\tat org.example.Script.load(Script.java:4)",
+			},
+			ok: true,
+		},
+		{
+			name:     "hostnames are not exceptions",
+			message:  "Failed to connect to nifi-0.nifi.svc:8443",
+			expected: javaException{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exception, ok := parseJavaException(tt.message)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, exception)
+		})
+	}
+}
+
+func TestTranslateBulletinEventsException(t *testing.T) {
+	const (
+		child   = "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01"
+		unknown = "d4c3b2a1-0f9e-4d8c-b7a6-958473625140"
+		matched = "7a0f1c2e-0000-4000-8000-000000000001"
+		warning = "7a0f1c2e-0000-4000-8000-000000000002"
+		early   = "7a0f1c2e-0000-4000-8000-000000000003"
+		message = "PublishKafka_2_6[id=0b6e5c9e-018e-1000-4e7a-d2f1c8b03e55] Failed to send FlowFile to Kafka: " +
+			"org.apache.kafka.common.errors.TimeoutException: Topic orders not present in metadata after 5000 ms."
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{})
+	et.TranslateProvenanceEvents(loadLineage(t, "synthetic_split.json"))
+
+	bulletins := []BulletinEvent{
+		newTestBulletin(matched, child, "ERROR"),
+		newTestBulletin(warning, child, "WARNING"),
+		newTestBulletin(early, unknown, "ERROR"),
+	}
+	for i := range bulletins {
+		bulletins[i].BulletinMessage = message
+	}
+	result := bulletinSpans(et.TranslateBulletinEvents(bulletins))
+
+	for _, id := range []string{matched, warning, early} {
+		span := result[id]
+		require.Equal(t, 1, span.Events().Len(), id)
+		assert.Equal(t, "exception", span.Events().At(0).Name())
+		attrs := span.Events().At(0).Attributes().AsRaw()
+		assert.Equal(t, "org.apache.kafka.common.errors.TimeoutException", attrs["exception.type"])
+		assert.Equal(t, "Topic orders not present in metadata after 5000 ms.", attrs["exception.message"])
+		assert.NotContains(t, attrs, "exception.stacktrace")
+	}
+
+	// the span status keeps the whole bulletin message
+	assert.Equal(t, message, result[matched].Status().Message())
+	assert.Empty(t, result[warning].Status().Message())
+
+	logs := et.TranslateBulletinEventsToLogs(bulletins[:1])
+	record := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, message, record.Body().Str())
+	exceptionType, ok := record.Attributes().Get("exception.type")
+	require.True(t, ok)
+	assert.Equal(t, "org.apache.kafka.common.errors.TimeoutException", exceptionType.Str())
+}
@@ -0,0 +1,198 @@
+package translator
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// bulletinHold holds the bulletins whose component has no span for their flowfile yet, bulletins
+// and provenance events are reported by separate reporting tasks so bulletins often come first
+type bulletinHold struct {
+	mu         sync.Mutex
+	timeout    time.Duration
+	maxEntries int
+	size       int
+	byFlowFile map[string][]heldBulletin
+	now        func() time.Time
+}
+
+type heldBulletin struct {
+	event    BulletinEvent
+	deadline time.Time
+}
+
+func newBulletinHold(timeout time.Duration, maxEntries int) *bulletinHold {
+	return &bulletinHold{
+		timeout:    timeout,
+		maxEntries: maxEntries,
+		byFlowFile: make(map[string][]heldBulletin),
+		now:        time.Now,
+	}
+}
+
+// add holds the bulletin until the timeout, returning false when the hold is full
+func (h *bulletinHold) add(event BulletinEvent) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.size >= h.maxEntries {
+		return false
+	}
+
+	h.byFlowFile[event.BulletinFlowFileUuid] = append(h.byFlowFile[event.BulletinFlowFileUuid],
+		heldBulletin{event: event, deadline: h.now().Add(h.timeout)})
+	h.size++
+	return true
+}
+
+// contains returns true if the bulletin is held
+func (h *bulletinHold) contains(event BulletinEvent) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := bulletinKey(event)
+	for _, bulletin := range h.byFlowFile[event.BulletinFlowFileUuid] {
+		if bulletinKey(bulletin.event) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// take removes and returns the bulletins held for the flowfile
+func (h *bulletinHold) take(flowFileID string) []BulletinEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	held, ok := h.byFlowFile[flowFileID]
+	if !ok {
+		return nil
+	}
+
+	delete(h.byFlowFile, flowFileID)
+	h.size -= len(held)
+
+	events := make([]BulletinEvent, 0, len(held))
+	for _, bulletin := range held {
+		events = append(events, bulletin.event)
+	}
+	return events
+}
+
+// expire removes and returns the bulletins held past their deadline
+func (h *bulletinHold) expire(now time.Time) []BulletinEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var expired []BulletinEvent
+	for flowFileID, held := range h.byFlowFile {
+		kept := held[:0]
+		for _, bulletin := range held {
+			if now.Before(bulletin.deadline) {
+				kept = append(kept, bulletin)
+			} else {
+				expired = append(expired, bulletin.event)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(h.byFlowFile, flowFileID)
+		} else {
+			h.byFlowFile[flowFileID] = kept
+		}
+	}
+	h.size -= len(expired)
+	return expired
+}
+
+// holdBulletin returns true if the bulletin is held, only the bulletins whose component has no
+// span for their flowfile yet are held so they can be recorded on that span once translated
+func (t *eventTranslator) holdBulletin(event BulletinEvent) bool {
+	if t.bulletinHold == nil {
+		return false
+	}
+
+	if _, ok := t.bulletinSpan(event); ok {
+		return false
+	}
+
+	if !t.bulletinHold.add(event) {
+		t.logger.Debug("bulletin hold is full, translating bulletin without its flowfile's span")
+		return false
+	}
+	return true
+}
+
+// attachHeldBulletins releases the bulletins held for the event's flowfile, the ones reported
+// by the event's component are recorded on its span, the others are kept in released for the
+// spans of the next events. It returns the bulletins taken from the hold
+func (t *eventTranslator) attachHeldBulletins(span ptrace.Span, event ProvenanceEvent, released map[string][]BulletinEvent) []BulletinEvent {
+	held := t.bulletinHold.take(event.EntityId)
+	if len(held) > 0 {
+		released[event.EntityId] = append(released[event.EntityId], held...)
+	}
+
+	bulletins, ok := released[event.EntityId]
+	if !ok {
+		return held
+	}
+
+	remaining := bulletins[:0]
+	for _, bulletin := range bulletins {
+		if bulletin.BulletinSourceId != event.ComponentId {
+			remaining = append(remaining, bulletin)
+			continue
+		}
+
+		timestamp := span.EndTimestamp()
+		if ts, err := time.Parse("2006-01-02T15:04:05.999Z", bulletin.BulletinTimestamp); err == nil {
+			timestamp = pcommon.NewTimestampFromTime(ts)
+		}
+		t.appendBulletinEvent(span, bulletin, timestamp)
+	}
+
+	if len(remaining) == 0 {
+		delete(released, event.EntityId)
+	} else {
+		released[event.EntityId] = remaining
+	}
+	return held
+}
+
+// ReleaseExpiredBulletins translates the bulletins held past the timeout into a ptrace.Traces,
+// they are correlated to their flowfile when its provenance events were translated meanwhile
+func (t *eventTranslator) ReleaseExpiredBulletins(now time.Time) (ptrace.Traces, []BulletinEvent) {
+	if t.bulletinHold == nil {
+		return ptrace.NewTraces(), nil
+	}
+
+	groups := newSpanGroups()
+	expired := t.bulletinHold.expire(now)
+	for _, event := range expired {
+		t.appendBulletinSpan(groups.slice(t.getResource(bulletinResourceFields(event))), event)
+	}
+	return groups.traces(), expired
+}
+
+// HoldBulletins returns released bulletins to the hold for another timeout, bulletins are
+// dropped when the hold is full
+func (t *eventTranslator) HoldBulletins(events []BulletinEvent) {
+	if t.bulletinHold == nil {
+		return
+	}
+
+	dropped := 0
+	for _, event := range events {
+		if !t.bulletinHold.add(event) {
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		t.logger.Warn("bulletin hold is full, dropping released bulletins", zap.Int("bulletins", dropped))
+	}
+}
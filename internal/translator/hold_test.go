@@ -0,0 +1,118 @@
+package translator
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+func TestTranslateBulletinEventsHold(t *testing.T) {
+	const (
+		send      = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a04"
+		child     = "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01"
+		unknown   = "d4c3b2a1-0f9e-4d8c-b7a6-958473625140"
+		matched   = "7a0f1c2e-0000-4000-8000-000000000001"
+		otherComp = "7a0f1c2e-0000-4000-8000-000000000002"
+		full      = "7a0f1c2e-0000-4000-8000-000000000003"
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{BulletinHoldTimeout: time.Minute, BulletinHoldMaxEntries: 2})
+
+	other := newTestBulletin(otherComp, child, "WARNING")
+	other.BulletinSourceId = "0b6e5c9e-018e-1000-4e7a-d2f1c8b03eff"
+
+	// the flowfiles are unknown, the bulletins are held until the hold is full
+	traces := et.TranslateBulletinEvents([]BulletinEvent{
+		newTestBulletin(matched, child, "ERROR"),
+		other,
+		newTestBulletin(full, unknown, "ERROR"),
+	})
+	require.Equal(t, 1, traces.SpanCount())
+	assert.Contains(t, bulletinSpans(traces), full)
+
+	traces = tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_split.json")))
+	spans := spansByEventID(traces)
+
+	// the bulletin is recorded on the span of the component that reported it
+	span := spans[send]
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+	require.Equal(t, 1, span.Events().Len())
+	assert.Equal(t, "exception", span.Events().At(0).Name())
+
+	// the others are correlated to their flowfile
+	result := bulletinSpans(traces)
+	require.Contains(t, result, otherComp)
+	assert.NotContains(t, result, matched)
+	assert.Equal(t, span.TraceID(), result[otherComp].TraceID())
+
+	assert.Equal(t, 0, tracesOf(et.ReleaseExpiredBulletins(time.Now().Add(2*time.Minute))).SpanCount())
+}
+
+func TestReleaseExpiredBulletins(t *testing.T) {
+	const (
+		unknown = "d4c3b2a1-0f9e-4d8c-b7a6-958473625140"
+		early   = "7a0f1c2e-0000-4000-8000-000000000004"
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{BulletinHoldTimeout: time.Minute, BulletinHoldMaxEntries: 10})
+	assert.Equal(t, 0, et.TranslateBulletinEvents([]BulletinEvent{newTestBulletin(early, unknown, "ERROR")}).SpanCount())
+
+	assert.Equal(t, 0, tracesOf(et.ReleaseExpiredBulletins(time.Now())).SpanCount())
+
+	// past the timeout the bulletin is translated without its flowfile's span
+	traces := tracesOf(et.ReleaseExpiredBulletins(time.Now().Add(2 * time.Minute)))
+	require.Equal(t, 1, traces.SpanCount())
+	span := bulletinSpans(traces)[early]
+	assert.Equal(t, uuidToTraceID(unknown), span.TraceID())
+	assert.True(t, span.ParentSpanID().IsEmpty())
+
+	assert.Equal(t, 0, tracesOf(et.ReleaseExpiredBulletins(time.Now().Add(2*time.Minute))).SpanCount())
+}
+
+func TestHoldBulletins(t *testing.T) {
+	const (
+		send    = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a04"
+		child   = "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01"
+		unknown = "d4c3b2a1-0f9e-4d8c-b7a6-958473625140"
+		matched = "7a0f1c2e-0000-4000-8000-000000000001"
+		early   = "7a0f1c2e-0000-4000-8000-000000000002"
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{
+		BulletinHoldTimeout:     time.Minute,
+		BulletinHoldMaxEntries:  10,
+		DeduplicationTTL:        time.Minute,
+		DeduplicationMaxEntries: 10,
+	})
+
+	// held bulletins are kept as seen when their batch is forgotten
+	bulletins := []BulletinEvent{newTestBulletin(matched, child, "ERROR")}
+	require.Len(t, et.DeduplicateBulletinEvents(component.DataTypeTraces, bulletins), 1)
+	assert.Equal(t, 0, et.TranslateBulletinEvents(bulletins).SpanCount())
+	et.ForgetBulletinEvents(component.DataTypeTraces, bulletins)
+	assert.Empty(t, et.DeduplicateBulletinEvents(component.DataTypeTraces, bulletins))
+
+	// bulletins released by a translation are recorded again once returned to the hold
+	lineage := loadLineage(t, "synthetic_split.json")
+	_, released := et.TranslateProvenanceEvents(slices.Clone(lineage))
+	require.Len(t, released, 1)
+	et.HoldBulletins(released)
+
+	span := spansByEventID(tracesOf(et.TranslateProvenanceEvents(lineage)))[send]
+	require.Equal(t, 1, span.Events().Len())
+	assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+
+	// expired bulletins returned to the hold are held for another timeout
+	assert.Equal(t, 0, et.TranslateBulletinEvents([]BulletinEvent{newTestBulletin(early, unknown, "ERROR")}).SpanCount())
+	_, released = et.ReleaseExpiredBulletins(time.Now().Add(2 * time.Minute))
+	require.Len(t, released, 1)
+	et.HoldBulletins(released)
+	assert.Equal(t, 0, tracesOf(et.ReleaseExpiredBulletins(time.Now())).SpanCount())
+	assert.Equal(t, 1, tracesOf(et.ReleaseExpiredBulletins(time.Now().Add(2*time.Minute))).SpanCount())
+}
@@ -0,0 +1,143 @@
+package translator
+
+import (
+	"maps"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ForkStrategy decides which trace the children of a FORK or CLONE event belong to
+type ForkStrategy string
+
+const (
+	// ForkSameTrace keeps the children in their parent's trace, as children of the FORK or CLONE span
+	ForkSameTrace ForkStrategy = "same_trace"
+
+	// ForkNewTraceLinked starts a trace per child, its first span links back to the FORK or CLONE span
+	ForkNewTraceLinked ForkStrategy = "new_trace_linked"
+)
+
+// JoinStrategy decides which trace the flowfile created by a JOIN event belongs to
+type JoinStrategy string
+
+const (
+	// JoinNewTraceLinked starts a trace for the merged flowfile, its JOIN span links to every parent
+	JoinNewTraceLinked JoinStrategy = "new_trace_linked"
+
+	// JoinAdoptFirstParent continues the trace of the first parent, the merged flowfile's spans
+	// descend from the parent's last span and the JOIN span still links to every parent
+	JoinAdoptFirstParent JoinStrategy = "adopt_first_parent"
+
+	// JoinAdoptOldestLineage continues the trace of the parent whose lineage started first
+	JoinAdoptOldestLineage JoinStrategy = "adopt_oldest_lineage"
+)
+
+// trackSpan updates the flowfile's entry once its span is created: the span becomes the
+// flowfile's last span, and the parent of its next event when chaining events. The first
+// span of a forked flowfile links back to the fork
+func (t *eventTranslator) trackSpan(event ProvenanceEvent, span ptrace.Span) {
+	// concurrent batches of the same flowfile update its entry atomically
+	t.spanContextTracking.Update(event.EntityId, func(entry *SpanContextEntry) {
+		entry.LastSpan = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID(span.TraceID()),
+			SpanID:  trace.SpanID(span.SpanID()),
+		})
+
+		if event.ComponentId != "" {
+			// entries are shared with concurrent readers, the map is copied before it is updated
+			components := make(map[string]trace.SpanContext, len(entry.ComponentSpans)+1)
+			maps.Copy(components, entry.ComponentSpans)
+			components[event.ComponentId] = entry.LastSpan
+			entry.ComponentSpans = components
+		}
+
+		if t.chainEvents {
+			// the span becomes the parent of the flowfile's next event
+			entry.SpanContext = entry.LastSpan
+		}
+
+		if entry.Lineage.StartMillis == 0 {
+			// remembered for adopt_oldest_lineage even when root spans are disabled
+			entry.Lineage.StartMillis = lineageStart(event)
+		}
+
+		if entry.RootSpanID.IsValid() {
+			entry.Lineage.Hops++
+			entry.Lineage.Bytes += event.EntitySize
+		}
+
+		if entry.ForkSpan.IsValid() {
+			appendLink(span, entry.ForkSpan, entry.ForkEntityID)
+			entry.ForkSpan = trace.SpanContext{}
+			entry.ForkEntityID = ""
+		}
+
+		if !entry.SpanContext.IsValid() {
+			// the flowfile wasn't tracked, keep the trace its span was assigned to
+			entry.SpanContext = trace.NewSpanContext(trace.SpanContextConfig{TraceID: entry.LastSpan.TraceID()})
+		}
+	})
+}
+
+// forkParent returns the parent of a forked child's spans: the FORK or CLONE span with
+// same_trace, the root of a new trace derived from the child's uuid with new_trace_linked
+func (t *eventTranslator) forkParent(childID string, forkSpan trace.SpanContext) trace.SpanContext {
+	if t.forkStrategy == ForkNewTraceLinked {
+		return trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID(uuidToTraceID(childID))})
+	}
+	return forkSpan
+}
+
+// adoptedParent returns the last span of the JOIN parent whose trace the merged flowfile
+// continues, false with new_trace_linked or when no parent is tracked
+func (t *eventTranslator) adoptedParent(event ProvenanceEvent) (trace.SpanContext, bool) {
+	var (
+		adopted trace.SpanContext
+		oldest  int64
+	)
+
+	for _, parent := range event.ParentIds {
+		if parent == event.EntityId {
+			continue
+		}
+
+		entry, ok := t.spanContextTracking.Get(parent)
+		if !ok || !entry.LastSpan.IsValid() {
+			continue
+		}
+
+		switch t.joinStrategy {
+		case JoinAdoptFirstParent:
+			return entry.LastSpan, true
+		case JoinAdoptOldestLineage:
+			if !adopted.IsValid() || entry.Lineage.StartMillis < oldest {
+				adopted = entry.LastSpan
+				oldest = entry.Lineage.StartMillis
+			}
+		default:
+			return trace.SpanContext{}, false
+		}
+	}
+	return adopted, adopted.IsValid()
+}
+
+// appendJoinLinks links the span of a JOIN event to the last span of each parent flowfile
+func (t *eventTranslator) appendJoinLinks(span ptrace.Span, event ProvenanceEvent) {
+	for _, parent := range event.ParentIds {
+		entry, ok := t.spanContextTracking.Get(parent)
+		if !ok || !entry.LastSpan.IsValid() {
+			continue
+		}
+		appendLink(span, entry.LastSpan, parent)
+	}
+}
+
+// appendLink links the span to the span of another flowfile
+func appendLink(span ptrace.Span, linked trace.SpanContext, entityID string) {
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(pcommon.TraceID(linked.TraceID()))
+	link.SetSpanID(pcommon.SpanID(linked.SpanID()))
+	link.Attributes().PutStr("nifi.entity.id", entityID)
+}
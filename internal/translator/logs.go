@@ -0,0 +1,91 @@
+package translator
+
+import (
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.uber.org/zap"
+)
+
+// TranslateBulletinEventsToLogs translates a slice of BulletinEvent into a plog.Logs,
+// unlike the traces translation, bulletins without a flowfile (e.g. controller services
+// and reporting tasks) are kept
+func (t *eventTranslator) TranslateBulletinEventsToLogs(events []BulletinEvent) plog.Logs {
+	groupByResource := make(map[string]plog.LogRecordSlice)
+	resources := make(map[string]resource)
+	observedTimestamp := pcommon.NewTimestampFromTime(time.Now())
+
+	for _, event := range events {
+		res := t.getResource(bulletinResourceFields(event))
+		slice, exist := groupByResource[res.key]
+		if !exist {
+			slice = plog.NewLogRecordSlice()
+			groupByResource[res.key] = slice
+			resources[res.key] = res
+		}
+
+		record := slice.AppendEmpty()
+		record.SetObservedTimestamp(observedTimestamp)
+		record.SetSeverityText(event.BulletinLevel)
+		record.SetSeverityNumber(getSeverityNumber(event.BulletinLevel))
+		message := t.attributeFilter.redactValue(redactionKeyBulletinMessage, event.BulletinMessage)
+		record.Body().SetStr(message)
+
+		ts, err := time.Parse("2006-01-02T15:04:05.999Z", event.BulletinTimestamp)
+		if err != nil {
+			t.logger.Warn("failed to parse timestamp for event",
+				zap.String("object.id", event.ObjectId),
+				zap.Int64("bulletin.id", event.BulletinId))
+		} else {
+			record.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+		}
+
+		if len(event.BulletinFlowFileUuid) > 0 {
+			if spanCtx, ok := t.bulletinSpan(event); ok {
+				// the span of the component that reported the bulletin
+				record.SetTraceID(pcommon.TraceID(spanCtx.TraceID()))
+				record.SetSpanID(pcommon.SpanID(spanCtx.SpanID()))
+			} else if ctx, ok := t.spanContextTracking.Get(event.BulletinFlowFileUuid); ok {
+				record.SetTraceID(pcommon.TraceID(ctx.SpanContext.TraceID()))
+				record.SetSpanID(pcommon.SpanID(ctx.SpanContext.SpanID()))
+			}
+		}
+
+		t.putBulletinAttributes(record.Attributes(), event)
+		if exception, ok := parseJavaException(message); ok {
+			putExceptionAttributes(record.Attributes(), exception, message)
+		}
+	}
+
+	results := plog.NewLogs()
+	for key, records := range groupByResource {
+		rl := results.ResourceLogs().AppendEmpty()
+		rl.SetSchemaUrl(semconv.SchemaURL)
+		resources[key].putAttributes(rl.Resource().Attributes())
+
+		in := rl.ScopeLogs().AppendEmpty()
+		setScopeInfo(in.Scope())
+		records.CopyTo(in.LogRecords())
+	}
+
+	return results
+}
+
+// getSeverityNumber returns the log severity matching a bulletin level
+func getSeverityNumber(level string) plog.SeverityNumber {
+	switch strings.ToLower(level) {
+	case "debug":
+		return plog.SeverityNumberDebug
+	case "info":
+		return plog.SeverityNumberInfo
+	case "warn", "warning":
+		return plog.SeverityNumberWarn
+	case "error":
+		return plog.SeverityNumberError
+	default:
+		return plog.SeverityNumberUnspecified
+	}
+}
@@ -0,0 +1,92 @@
+package translator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.uber.org/zap"
+)
+
+func TestTranslateBulletinEventsToLogsSeverity(t *testing.T) {
+	tests := []struct {
+		level    string
+		severity plog.SeverityNumber
+	}{
+		{level: "ERROR", severity: plog.SeverityNumberError},
+		{level: "WARNING", severity: plog.SeverityNumberWarn},
+		{level: "WARN", severity: plog.SeverityNumberWarn},
+		{level: "INFO", severity: plog.SeverityNumberInfo},
+		{level: "DEBUG", severity: plog.SeverityNumberDebug},
+		{level: "debug", severity: plog.SeverityNumberDebug},
+		{level: "TRACE", severity: plog.SeverityNumberUnspecified},
+		{level: "", severity: plog.SeverityNumberUnspecified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			et := NewEventTranslator(zap.NewNop(), Settings{})
+			logs := et.TranslateBulletinEventsToLogs([]BulletinEvent{
+				newTestBulletin("7a0f1c2e-0000-4000-8000-000000000001", "", tt.level),
+			})
+
+			require.Equal(t, 1, logs.LogRecordCount())
+			record := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, tt.severity, record.SeverityNumber())
+			assert.Equal(t, tt.level, record.SeverityText())
+		})
+	}
+}
+
+func TestTranslateBulletinEventsToLogs(t *testing.T) {
+	const (
+		entity  = "1b6a4a7e-2a4e-4a4e-9f0e-6d0a6d4f1c11"
+		unknown = "d4c3b2a1-0f9e-4d8c-b7a6-958473625140"
+	)
+
+	store := NewLRUSpanContextStore(defaultSpanContextTTL, defaultSpanContextMaxEntries)
+	tracked := newTestEntry()
+	store.Set(entity, tracked)
+
+	tests := []struct {
+		name       string
+		flowFileID string
+		traceID    pcommon.TraceID
+		spanID     pcommon.SpanID
+	}{
+		{
+			name:       "tracked flowfile",
+			flowFileID: entity,
+			traceID:    pcommon.TraceID(tracked.SpanContext.TraceID()),
+			spanID:     pcommon.SpanID(tracked.SpanContext.SpanID()),
+		},
+		{
+			name:       "unknown flowfile",
+			flowFileID: unknown,
+		},
+		{
+			name: "no flowfile",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			et := NewEventTranslator(zap.NewNop(), Settings{SpanContextStore: store})
+			bulletin := newTestBulletin("7a0f1c2e-0000-4000-8000-000000000001", tt.flowFileID, "ERROR")
+			logs := et.TranslateBulletinEventsToLogs([]BulletinEvent{bulletin})
+
+			require.Equal(t, 1, logs.LogRecordCount())
+			record := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, bulletin.BulletinMessage, record.Body().Str())
+			assert.Equal(t, "2024-04-05T10:20:00.7Z", record.Timestamp().AsTime().Format("2006-01-02T15:04:05.999Z"))
+			assert.Equal(t, tt.traceID, record.TraceID())
+			assert.Equal(t, tt.spanID, record.SpanID())
+
+			level, ok := record.Attributes().Get("nifi.bulletin.level")
+			require.True(t, ok)
+			assert.Equal(t, "ERROR", level.Str())
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package translator
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	metricComponentEvents        = "nifi.component.events"
+	metricComponentBytes         = "nifi.component.bytes"
+	metricComponentPreviousBytes = "nifi.component.previous_bytes"
+	metricComponentDuration      = "nifi.component.event.duration"
+	metricProcessGroupEvents     = "nifi.process_group.events"
+	metricFlowFileLineage        = "nifi.flowfile.lineage.duration"
+)
+
+var (
+	// durationBounds are the histogram bucket boundaries (in milliseconds) for event durations
+	durationBounds = []float64{0, 5, 10, 25, 50, 75, 100, 250, 500, 750, 1000, 2500, 5000, 7500, 10000}
+
+	// lineageBounds are the histogram bucket boundaries (in milliseconds) for flowfile end-to-end latency
+	lineageBounds = []float64{100, 500, 1000, 5000, 10000, 30000, 60000, 300000, 600000, 1800000, 3600000, 21600000, 86400000}
+)
+
+// metricSeriesRetention is how long the end of a series' last data point is remembered,
+// relative to the newest data point
+const metricSeriesRetention = time.Hour
+
+// metricDataPoint accumulates the values of a single data point within a batch
+type metricDataPoint struct {
+	attributes [][2]string
+	start, end int64
+	sum        int64
+	count      uint64
+	buckets    []uint64
+	min, max   int64
+}
+
+// metricsAccumulator aggregates provenance events into delta metrics, grouped by resource
+type metricsAccumulator struct {
+	resources map[string]resource
+	services  map[string]map[string]map[string]*metricDataPoint
+}
+
+func newMetricsAccumulator() *metricsAccumulator {
+	return &metricsAccumulator{
+		resources: make(map[string]resource),
+		services:  make(map[string]map[string]map[string]*metricDataPoint),
+	}
+}
+
+// dataPoint returns the data point for the metric and attributes, creating it if needed
+func (a *metricsAccumulator) dataPoint(res resource, metric string, timestamp int64, attrs ...[2]string) *metricDataPoint {
+	metrics, ok := a.services[res.key]
+	if !ok {
+		metrics = make(map[string]map[string]*metricDataPoint)
+		a.services[res.key] = metrics
+		a.resources[res.key] = res
+	}
+
+	points, ok := metrics[metric]
+	if !ok {
+		points = make(map[string]*metricDataPoint)
+		metrics[metric] = points
+	}
+
+	var sb strings.Builder
+	for _, attr := range attrs {
+		sb.WriteString(attr[0])
+		sb.WriteByte('=')
+		sb.WriteString(attr[1])
+		sb.WriteByte(0)
+	}
+
+	key := sb.String()
+	dp, ok := points[key]
+	if !ok {
+		dp = &metricDataPoint{attributes: attrs, start: timestamp, end: timestamp}
+		points[key] = dp
+	}
+
+	dp.start = min(dp.start, timestamp)
+	dp.end = max(dp.end, timestamp)
+	return dp
+}
+
+func (a *metricsAccumulator) addSum(res resource, metric string, timestamp, value int64, attrs ...[2]string) {
+	dp := a.dataPoint(res, metric, timestamp, attrs...)
+	dp.sum += value
+}
+
+func (a *metricsAccumulator) addHistogram(res resource, metric string, timestamp, value int64, bounds []float64, attrs ...[2]string) {
+	dp := a.dataPoint(res, metric, timestamp, attrs...)
+	if dp.buckets == nil {
+		dp.buckets = make([]uint64, len(bounds)+1)
+		dp.min, dp.max = value, value
+	}
+
+	idx, _ := slices.BinarySearch(bounds, float64(value))
+	dp.buckets[idx]++
+	dp.count++
+	dp.sum += value
+	dp.min = min(dp.min, value)
+	dp.max = max(dp.max, value)
+}
+
+// metricSeries remembers the end of the last data point of each series, so the delta
+// data points of consecutive batches cover contiguous, non-overlapping intervals
+type metricSeries struct {
+	mu   sync.Mutex
+	ends map[string]int64
+}
+
+func newMetricSeries() *metricSeries {
+	return &metricSeries{ends: make(map[string]int64)}
+}
+
+// interval returns the interval in milliseconds of the series' next data point, from the
+// end of its last data point to the millisecond after the last event, which is exclusive.
+// A new series starts at its first event
+func (s *metricSeries) interval(key string, dp *metricDataPoint) (int64, int64) {
+	start, ok := s.ends[key]
+	if !ok {
+		start = dp.start
+	}
+
+	end := max(dp.end+1, start+1)
+	s.ends[key] = end
+	return start, end
+}
+
+// prune forgets the series without data points for metricSeriesRetention before newest
+func (s *metricSeries) prune(newest int64) {
+	for key, end := range s.ends {
+		if end < newest-metricSeriesRetention.Milliseconds() {
+			delete(s.ends, key)
+		}
+	}
+}
+
+// TranslateProvenanceEventsToMetrics aggregates a slice of ProvenanceEvent into delta metrics
+func (t *eventTranslator) TranslateProvenanceEventsToMetrics(events []ProvenanceEvent) pmetric.Metrics {
+	acc := newMetricsAccumulator()
+	for _, event := range events {
+		if t.shouldIgnore(event) {
+			continue
+		}
+
+		res := t.getResource(provenanceResourceFields(event))
+		eventType := [2]string{"nifi.event.type", string(event.EventType)}
+		component := [][2]string{
+			{"nifi.component.id", event.ComponentId},
+			{"nifi.component.name", event.ComponentName},
+			{"nifi.component.type", event.ComponentType},
+			{"nifi.process.group.id", event.ProcessGroupId},
+		}
+		componentWithType := append(slices.Clone(component), eventType)
+
+		acc.addSum(res, metricComponentEvents, event.TimestampMillis, 1, componentWithType...)
+		acc.addSum(res, metricComponentBytes, event.TimestampMillis, event.EntitySize, componentWithType...)
+		acc.addSum(res, metricComponentPreviousBytes, event.TimestampMillis, event.PreviousEntitySize, componentWithType...)
+		// NiFi reports -1 when the duration is unknown
+		if event.DurationMillis >= 0 {
+			acc.addHistogram(res, metricComponentDuration, event.TimestampMillis, event.DurationMillis, durationBounds, componentWithType...)
+		}
+		acc.addSum(res, metricProcessGroupEvents, event.TimestampMillis, 1,
+			[2]string{"nifi.process.group.id", event.ProcessGroupId},
+			[2]string{"nifi.process.group.name", event.ProcessGroupName},
+			eventType)
+
+		if event.EventType == ProvenanceEventTypeDrop && event.LineageStart > 0 {
+			acc.addHistogram(res, metricFlowFileLineage, event.TimestampMillis,
+				event.TimestampMillis-event.LineageStart, lineageBounds, component...)
+		}
+	}
+
+	return acc.toMetrics(t.metricSeries)
+}
+
+// toMetrics converts the accumulated data points into pmetric.Metrics, their intervals
+// follow the previous data points of their series
+func (a *metricsAccumulator) toMetrics(series *metricSeries) pmetric.Metrics {
+	series.mu.Lock()
+	defer series.mu.Unlock()
+
+	var newest int64
+	results := pmetric.NewMetrics()
+	for _, key := range sortedKeys(a.services) {
+		rm := results.ResourceMetrics().AppendEmpty()
+		rm.SetSchemaUrl(semconv.SchemaURL)
+		a.resources[key].putAttributes(rm.Resource().Attributes())
+
+		sm := rm.ScopeMetrics().AppendEmpty()
+		setScopeInfo(sm.Scope())
+
+		metrics := a.services[key]
+		for _, name := range sortedKeys(metrics) {
+			m := sm.Metrics().AppendEmpty()
+			m.SetName(name)
+
+			points := metrics[name]
+			intervals := make(map[string][2]int64, len(points))
+			for pointKey, dp := range points {
+				start, end := series.interval(key+name+"\x00"+pointKey, dp)
+				intervals[pointKey] = [2]int64{start, end}
+				newest = max(newest, end)
+			}
+
+			switch name {
+			case metricComponentEvents:
+				m.SetDescription("Number of provenance events reported by a component")
+				m.SetUnit("{event}")
+				appendSumDataPoints(m, points, intervals)
+			case metricProcessGroupEvents:
+				m.SetDescription("Number of provenance events reported within a process group")
+				m.SetUnit("{event}")
+				appendSumDataPoints(m, points, intervals)
+			case metricComponentBytes:
+				m.SetDescription("Size of the flowfiles handled by a component")
+				m.SetUnit("By")
+				appendSumDataPoints(m, points, intervals)
+			case metricComponentPreviousBytes:
+				m.SetDescription("Size of the flowfiles handled by a component, before the event")
+				m.SetUnit("By")
+				appendSumDataPoints(m, points, intervals)
+			case metricComponentDuration:
+				m.SetDescription("Duration of the provenance events reported by a component")
+				m.SetUnit("ms")
+				appendHistogramDataPoints(m, points, intervals, durationBounds)
+			case metricFlowFileLineage:
+				m.SetDescription("Time from the start of a flowfile's lineage until it was dropped")
+				m.SetUnit("ms")
+				appendHistogramDataPoints(m, points, intervals, lineageBounds)
+			}
+		}
+	}
+
+	series.prune(newest)
+	return results
+}
+
+func appendSumDataPoints(m pmetric.Metric, points map[string]*metricDataPoint, intervals map[string][2]int64) {
+	sum := m.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+
+	for _, key := range sortedKeys(points) {
+		dp := points[key]
+		ndp := sum.DataPoints().AppendEmpty()
+		ndp.SetStartTimestamp(pcommon.Timestamp(intervals[key][0] * 1000000))
+		ndp.SetTimestamp(pcommon.Timestamp(intervals[key][1] * 1000000))
+		ndp.SetIntValue(dp.sum)
+		putDataPointAttributes(ndp.Attributes(), dp)
+	}
+}
+
+func appendHistogramDataPoints(m pmetric.Metric, points map[string]*metricDataPoint, intervals map[string][2]int64, bounds []float64) {
+	histogram := m.SetEmptyHistogram()
+	histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+
+	for _, key := range sortedKeys(points) {
+		dp := points[key]
+		ndp := histogram.DataPoints().AppendEmpty()
+		ndp.SetStartTimestamp(pcommon.Timestamp(intervals[key][0] * 1000000))
+		ndp.SetTimestamp(pcommon.Timestamp(intervals[key][1] * 1000000))
+		ndp.SetCount(dp.count)
+		ndp.SetSum(float64(dp.sum))
+		ndp.SetMin(float64(dp.min))
+		ndp.SetMax(float64(dp.max))
+		ndp.ExplicitBounds().FromRaw(bounds)
+		ndp.BucketCounts().FromRaw(dp.buckets)
+		putDataPointAttributes(ndp.Attributes(), dp)
+	}
+}
+
+func putDataPointAttributes(attrs pcommon.Map, dp *metricDataPoint) {
+	for _, attr := range dp.attributes {
+		attrs.PutStr(attr[0], attr[1])
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
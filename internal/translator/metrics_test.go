@@ -0,0 +1,114 @@
+package translator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+)
+
+func TestTranslateProvenanceEventsToMetrics(t *testing.T) {
+	et := NewEventTranslator(zap.NewNop(), Settings{})
+	events := []ProvenanceEvent{
+		{
+			EventType:        ProvenanceEventTypeCreate,
+			TimestampMillis:  1000,
+			DurationMillis:   4,
+			LineageStart:     1000,
+			ComponentId:      "generate",
+			ProcessGroupName: "ingest",
+			EntitySize:       100,
+		},
+		{
+			EventType:          ProvenanceEventTypeContentModified,
+			TimestampMillis:    1500,
+			DurationMillis:     30,
+			LineageStart:       1000,
+			ComponentId:        "transform",
+			ProcessGroupName:   "ingest",
+			EntitySize:         200,
+			PreviousEntitySize: 100,
+		},
+		{
+			EventType:        ProvenanceEventTypeFetch,
+			TimestampMillis:  2000,
+			DurationMillis:   -1,
+			ComponentId:      "fetch",
+			ProcessGroupName: "ingest",
+		},
+		{
+			EventType:        ProvenanceEventTypeDrop,
+			TimestampMillis:  3000,
+			LineageStart:     1000,
+			ComponentId:      "transform",
+			ProcessGroupName: "ingest",
+			EntitySize:       200,
+		},
+	}
+
+	metrics := et.TranslateProvenanceEventsToMetrics(events)
+	require.Equal(t, 1, metrics.ResourceMetrics().Len())
+
+	byName := make(map[string]pmetric.Metric)
+	ms := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	for i := 0; i < ms.Len(); i++ {
+		byName[ms.At(i).Name()] = ms.At(i)
+	}
+
+	assert.Equal(t, 4, byName[metricComponentEvents].Sum().DataPoints().Len())
+	assert.Equal(t, 4, byName[metricProcessGroupEvents].Sum().DataPoints().Len())
+
+	// unknown durations are left out of the histogram
+	durations := byName[metricComponentDuration].Histogram().DataPoints()
+	require.Equal(t, 3, durations.Len())
+	for i := 0; i < durations.Len(); i++ {
+		assert.GreaterOrEqual(t, durations.At(i).Sum(), float64(0))
+	}
+
+	lineage := byName[metricFlowFileLineage].Histogram().DataPoints()
+	require.Equal(t, 1, lineage.Len())
+	assert.Equal(t, uint64(1), lineage.At(0).Count())
+	assert.Equal(t, float64(2000), lineage.At(0).Sum())
+
+	var totalBytes int64
+	bytes := byName[metricComponentBytes].Sum().DataPoints()
+	for i := 0; i < bytes.Len(); i++ {
+		totalBytes += bytes.At(i).IntValue()
+	}
+	assert.Equal(t, int64(500), totalBytes)
+}
+
+func TestTranslateProvenanceEventsToMetricsIntervals(t *testing.T) {
+	et := NewEventTranslator(zap.NewNop(), Settings{})
+	event := ProvenanceEvent{EventType: ProvenanceEventTypeCreate, TimestampMillis: 1000, ComponentId: "generate", ProcessGroupName: "ingest"}
+
+	points := func(metrics pmetric.Metrics) pmetric.NumberDataPointSlice {
+		ms := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		for i := 0; i < ms.Len(); i++ {
+			if ms.At(i).Name() == metricComponentEvents {
+				return ms.At(i).Sum().DataPoints()
+			}
+		}
+		t.Fatal("missing metric")
+		return pmetric.NumberDataPointSlice{}
+	}
+
+	// a single event covers its millisecond
+	first := points(et.TranslateProvenanceEventsToMetrics([]ProvenanceEvent{event})).At(0)
+	assert.Equal(t, int64(1000), first.StartTimestamp().AsTime().UnixMilli())
+	assert.Equal(t, int64(1001), first.Timestamp().AsTime().UnixMilli())
+
+	// the next batch starts where the previous one ended, even with late events
+	late := event
+	late.TimestampMillis = 900
+	event.TimestampMillis = 2000
+	second := points(et.TranslateProvenanceEventsToMetrics([]ProvenanceEvent{late, event})).At(0)
+	assert.Equal(t, first.Timestamp(), second.StartTimestamp())
+	assert.Equal(t, int64(2001), second.Timestamp().AsTime().UnixMilli())
+
+	third := points(et.TranslateProvenanceEventsToMetrics([]ProvenanceEvent{late})).At(0)
+	assert.Equal(t, second.Timestamp(), third.StartTimestamp())
+	assert.Greater(t, third.Timestamp(), third.StartTimestamp())
+}
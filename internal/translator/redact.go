@@ -0,0 +1,188 @@
+package translator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// RedactionAction is how a redaction rule rewrites a value
+type RedactionAction string
+
+const (
+	// RedactionMask replaces the value with a fixed mask
+	RedactionMask RedactionAction = "mask"
+
+	// RedactionHash replaces the value with the hex SHA-256 of the salt and the value, so
+	// equal values can still be correlated
+	RedactionHash RedactionAction = "hash"
+
+	// RedactionTruncate keeps the first MaxLength characters of the value
+	RedactionTruncate RedactionAction = "truncate"
+)
+
+// redactionMask replaces masked values
+const redactionMask = "****"
+
+// regexPatternPrefix marks a pattern as a regular expression, other patterns are globs
+const regexPatternPrefix = "regex:"
+
+// The keys of the event fields redaction rules apply to, along with flowfile attributes
+const (
+	redactionKeyDetails         = "nifi.event.details"
+	redactionKeyTransitUri      = "nifi.transit.uri"
+	redactionKeyBulletinMessage = "nifi.bulletin.message"
+)
+
+// RedactionRule rewrites the values of the matching keys
+type RedactionRule struct {
+	// Keys are glob patterns, or regular expressions prefixed with "regex:"
+	Keys []string
+
+	Action RedactionAction
+
+	// MaxLength is the length values are truncated to with RedactionTruncate
+	MaxLength int
+}
+
+// AttributeFilterSettings configures which flowfile attributes are kept on spans and how
+// the values of attributes and event fields are redacted
+type AttributeFilterSettings struct {
+	// Include keeps only the matching flowfile attributes, all of them when empty
+	Include []string
+
+	// Exclude drops the matching flowfile attributes, even when included
+	Exclude []string
+
+	// Redact are applied in order to flowfile attributes and to the details, transit uri and
+	// bulletin message keyed as nifi.event.details, nifi.transit.uri and nifi.bulletin.message,
+	// the first matching rule wins
+	Redact []RedactionRule
+
+	// HashSalt is prepended to values hashed by RedactionHash
+	HashSalt string
+}
+
+// AttributeFilter filters and redacts flowfile attributes and event fields
+type AttributeFilter struct {
+	include  []keyMatcher
+	exclude  []keyMatcher
+	redact   []compiledRedactionRule
+	hashSalt string
+}
+
+type compiledRedactionRule struct {
+	keys      []keyMatcher
+	action    RedactionAction
+	maxLength int
+}
+
+// keyMatcher matches attribute keys against a glob or a regular expression
+type keyMatcher func(key string) bool
+
+// NewAttributeFilter compiles the patterns and rules of the settings
+func NewAttributeFilter(settings AttributeFilterSettings) (*AttributeFilter, error) {
+	include, err := compileKeyMatchers(settings.Include)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	exclude, err := compileKeyMatchers(settings.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+
+	f := &AttributeFilter{include: include, exclude: exclude, hashSalt: settings.HashSalt}
+	for i, rule := range settings.Redact {
+		switch rule.Action {
+		case RedactionMask, RedactionHash:
+		case RedactionTruncate:
+			if rule.MaxLength <= 0 {
+				return nil, fmt.Errorf("redact[%d]: max_length must be positive to truncate", i)
+			}
+		default:
+			return nil, fmt.Errorf("redact[%d]: unknown action %q", i, rule.Action)
+		}
+
+		keys, err := compileKeyMatchers(rule.Keys)
+		if err != nil {
+			return nil, fmt.Errorf("redact[%d]: %w", i, err)
+		}
+		f.redact = append(f.redact, compiledRedactionRule{keys: keys, action: rule.Action, maxLength: rule.MaxLength})
+	}
+	return f, nil
+}
+
+func compileKeyMatchers(patterns []string) ([]keyMatcher, error) {
+	matchers := make([]keyMatcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		if expr, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, err
+			}
+			matchers = append(matchers, re.MatchString)
+			continue
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		glob := pattern
+		matchers = append(matchers, func(key string) bool {
+			matched, _ := path.Match(glob, key)
+			return matched
+		})
+	}
+	return matchers, nil
+}
+
+func matchesAny(matchers []keyMatcher, key string) bool {
+	for _, match := range matchers {
+		if match(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// keep returns true if the flowfile attribute is kept on spans, a nil filter keeps every attribute
+func (f *AttributeFilter) keep(key string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.include) > 0 && !matchesAny(f.include, key) {
+		return false
+	}
+	return !matchesAny(f.exclude, key)
+}
+
+// redactValue returns the value rewritten by the first rule matching the key
+func (f *AttributeFilter) redactValue(key, value string) string {
+	if f == nil || value == "" {
+		return value
+	}
+
+	for _, rule := range f.redact {
+		if !matchesAny(rule.keys, key) {
+			continue
+		}
+
+		switch rule.action {
+		case RedactionMask:
+			return redactionMask
+		case RedactionHash:
+			sum := sha256.Sum256([]byte(f.hashSalt + value))
+			return hex.EncodeToString(sum[:])
+		case RedactionTruncate:
+			if runes := []rune(value); len(runes) > rule.maxLength {
+				return string(runes[:rule.maxLength])
+			}
+			return value
+		}
+	}
+	return value
+}
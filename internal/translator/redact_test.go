@@ -0,0 +1,103 @@
+package translator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestAttributeFilter(t *testing.T) {
+	filter, err := NewAttributeFilter(AttributeFilterSettings{
+		Exclude: []string{"http.headers.*", "regex:(?i)^e-?mail$"},
+		Redact: []RedactionRule{
+			{Keys: []string{"customer.id"}, Action: RedactionHash},
+			{Keys: []string{"filename", "nifi.transit.uri"}, Action: RedactionMask},
+			{Keys: []string{"regex:^nifi\\.(event\\.details|bulletin\\.message)$"}, Action: RedactionTruncate, MaxLength: 8},
+		},
+		HashSalt: "pepper",
+	})
+	require.NoError(t, err)
+
+	assert.True(t, filter.keep("filename"))
+	assert.False(t, filter.keep("http.headers.Authorization"))
+	assert.False(t, filter.keep("Email"))
+	assert.True(t, filter.keep("email.domain"))
+
+	sum := sha256.Sum256([]byte("pepper" + "c-1042"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), filter.redactValue("customer.id", "c-1042"))
+	assert.Equal(t, "****", filter.redactValue("filename", "jane.doe-invoice.pdf"))
+	assert.Equal(t, "Auto-Ter", filter.redactValue(redactionKeyDetails, "Auto-Terminated by original Relationship"))
+	assert.Equal(t, "short", filter.redactValue(redactionKeyBulletinMessage, "short"))
+	assert.Equal(t, "orders", filter.redactValue("path", "orders"))
+	assert.Equal(t, "", filter.redactValue("filename", ""))
+
+	// include keeps only the matching attributes, exclude still applies
+	filter, err = NewAttributeFilter(AttributeFilterSettings{
+		Include: []string{"kafka.*", "filename"},
+		Exclude: []string{"kafka.key"},
+	})
+	require.NoError(t, err)
+	assert.True(t, filter.keep("kafka.topic"))
+	assert.True(t, filter.keep("filename"))
+	assert.False(t, filter.keep("kafka.key"))
+	assert.False(t, filter.keep("path"))
+
+	var nilFilter *AttributeFilter
+	assert.True(t, nilFilter.keep("anything"))
+	assert.Equal(t, "value", nilFilter.redactValue("anything", "value"))
+}
+
+func TestNewAttributeFilterErrors(t *testing.T) {
+	_, err := NewAttributeFilter(AttributeFilterSettings{Include: []string{"regex:("}})
+	assert.Error(t, err)
+
+	_, err = NewAttributeFilter(AttributeFilterSettings{Exclude: []string{"["}})
+	assert.Error(t, err)
+
+	_, err = NewAttributeFilter(AttributeFilterSettings{Redact: []RedactionRule{{Keys: []string{"*"}, Action: "encrypt"}}})
+	assert.Error(t, err)
+
+	_, err = NewAttributeFilter(AttributeFilterSettings{Redact: []RedactionRule{{Keys: []string{"*"}, Action: RedactionTruncate}}})
+	assert.Error(t, err)
+}
+
+func TestTranslateEventsRedaction(t *testing.T) {
+	filter, err := NewAttributeFilter(AttributeFilterSettings{
+		Exclude: []string{"path"},
+		Redact: []RedactionRule{
+			{Keys: []string{"filename", "nifi.transit.uri", "nifi.bulletin.message"}, Action: RedactionMask},
+		},
+	})
+	require.NoError(t, err)
+	et := NewEventTranslator(zap.NewNop(), Settings{Attributes: filter})
+
+	// RECEIVE of file:/data/inbox/orders-0405.csv
+	spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_split.json"))))
+	attrs := spans["6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a01"].Attributes().AsRaw()
+	assert.Equal(t, "****", attrs["nifi.attributes.filename"])
+	assert.NotContains(t, attrs, "nifi.attributes.path")
+	assert.Equal(t, "****", attrs["nifi.transit.uri"])
+	assert.Equal(t, "****", attrs["file.path"])
+	assert.Equal(t, "****", attrs["file.name"])
+	assert.Equal(t, "file", attrs["url.scheme"])
+
+	bulletins := []BulletinEvent{{
+		ObjectId:             "0b6e5c9e-018e-1000-4e7a-d2f1c8b03e55",
+		BulletinLevel:        "ERROR",
+		BulletinMessage:      "failed to deliver orders-0405.csv to jane.doe@example.com",
+		BulletinTimestamp:    "2024-04-05T10:00:00.000Z",
+		BulletinFlowFileUuid: "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01",
+	}}
+
+	traces := et.TranslateBulletinEvents(bulletins)
+	message, ok := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get("nifi.bulletin.message")
+	require.True(t, ok)
+	assert.Equal(t, "****", message.Str())
+
+	logs := et.TranslateBulletinEventsToLogs(bulletins)
+	assert.Equal(t, "****", logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
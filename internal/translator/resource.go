@@ -0,0 +1,290 @@
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.uber.org/zap"
+)
+
+// ResourceFields are the fields of an event the service name and the resource are derived from
+type ResourceFields struct {
+	ProcessGroupID   string
+	ProcessGroupName string
+
+	// ProcessGroupPath is the path of the process group from the root, e.g. "NiFi Flow / tenants / acme",
+	// only reported with bulletins
+	ProcessGroupPath string
+
+	// RootProcessGroup is the name of the root process group
+	RootProcessGroup string
+
+	ComponentID   string
+	ComponentName string
+	ComponentType string
+	Platform      string
+	Application   string
+	Hostname      string
+
+	// NodeID identifies the cluster node, only reported with bulletins
+	NodeID string
+
+	// Attributes are the flowfile's attributes, only reported with provenance events
+	Attributes map[string]string
+}
+
+// groupPathSeparator separates the process groups of a bulletin's group path
+const groupPathSeparator = "/"
+
+// provenanceResourceFields returns the resource fields of a provenance event, the
+// reporting task reports the name of the root process group as the application
+func provenanceResourceFields(event ProvenanceEvent) ResourceFields {
+	return ResourceFields{
+		ProcessGroupID:   event.ProcessGroupId,
+		ProcessGroupName: event.ProcessGroupName,
+		RootProcessGroup: event.Application,
+		ComponentID:      event.ComponentId,
+		ComponentName:    event.ComponentName,
+		ComponentType:    event.ComponentType,
+		Platform:         event.Platform,
+		Application:      event.Application,
+		Hostname:         event.ActorHostname,
+		Attributes:       event.UpdatedAttributes,
+	}
+}
+
+// bulletinResourceFields returns the resource fields of a bulletin event
+func bulletinResourceFields(event BulletinEvent) ResourceFields {
+	return ResourceFields{
+		ProcessGroupID:   event.BulletinGroupId,
+		ProcessGroupName: event.BulletinGroupName,
+		ProcessGroupPath: event.BulletinGroupPath,
+		RootProcessGroup: pathPrefix(event.BulletinGroupPath, 1),
+		ComponentID:      event.BulletinSourceId,
+		ComponentName:    event.BulletinSourceName,
+		ComponentType:    event.BulletinSourceType,
+		Platform:         event.Platform,
+		Hostname:         event.BulletinNodeAddress,
+		NodeID:           event.BulletinNodeId,
+	}
+}
+
+// ServiceNameTemplate derives service.name from the ResourceFields of an event
+type ServiceNameTemplate struct {
+	tmpl *template.Template
+}
+
+// ParseServiceNameTemplate parses a text/template over ResourceFields, e.g.
+// `{{ index .Attributes "tenant" | default .Platform }}` or `{{ pathPrefix .ProcessGroupPath 2 }}`
+func ParseServiceNameTemplate(text string) (*ServiceNameTemplate, error) {
+	tmpl, err := template.New("service_name").
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{
+			"pathPrefix": pathPrefix,
+			"default":    defaultValue,
+		}).
+		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceNameTemplate{tmpl: tmpl}, nil
+}
+
+// execute renders the service name of the fields
+func (s *ServiceNameTemplate) execute(fields ResourceFields) (string, error) {
+	var sb strings.Builder
+	if err := s.tmpl.Execute(&sb, fields); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// pathPrefix returns the first n process groups of a group path
+func pathPrefix(path string, n int) string {
+	groups := strings.Split(path, groupPathSeparator)
+	for i := range groups {
+		groups[i] = strings.TrimSpace(groups[i])
+	}
+	return strings.Join(groups[:min(n, len(groups))], " "+groupPathSeparator+" ")
+}
+
+// defaultValue returns value, or def when value is empty
+func defaultValue(def, value string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// resourceKeyAttributesPrefix selects a flowfile attribute as a resource key
+const resourceKeyAttributesPrefix = "nifi.attributes."
+
+// attributeProcessGroupPath is the process group path of bulletins, kept on their resource only
+const attributeProcessGroupPath = "nifi.process_group.path"
+
+// resourceKeyFields are the fields that can be added to the resource, named after the span attribute they match
+var resourceKeyFields = map[string]func(ResourceFields) string{
+	"nifi.process.group.id":   func(f ResourceFields) string { return f.ProcessGroupID },
+	"nifi.process.group.name": func(f ResourceFields) string { return f.ProcessGroupName },
+	attributeProcessGroupPath: func(f ResourceFields) string { return f.ProcessGroupPath },
+	"nifi.process.group.root": func(f ResourceFields) string { return f.RootProcessGroup },
+	"nifi.component.id":       func(f ResourceFields) string { return f.ComponentID },
+	"nifi.component.name":     func(f ResourceFields) string { return f.ComponentName },
+	"nifi.component.type":     func(f ResourceFields) string { return f.ComponentType },
+	"nifi.platform":           func(f ResourceFields) string { return f.Platform },
+	"nifi.application":        func(f ResourceFields) string { return f.Application },
+	"nifi.hostname":           func(f ResourceFields) string { return f.Hostname },
+}
+
+// ValidateResourceKey returns an error if the key can't be added to the resource
+func ValidateResourceKey(key string) error {
+	if _, ok := resourceKeyFields[key]; ok {
+		return nil
+	}
+	if strings.HasPrefix(key, resourceKeyAttributesPrefix) && len(key) > len(resourceKeyAttributesPrefix) {
+		return nil
+	}
+	return fmt.Errorf("unknown resource key %q", key)
+}
+
+// resource is the resource events are grouped under
+type resource struct {
+	// key identifies the resource, events with the same key share a ResourceSpans
+	key        string
+	attributes [][2]string
+}
+
+// getResource returns the resource of an event, its service name is rendered from the
+// template when configured and falls back to the process group name. The resource
+// identifies the node and process group along with the configured keys
+func (t *eventTranslator) getResource(fields ResourceFields) resource {
+	serviceName := fields.ProcessGroupName
+	if t.serviceName != nil {
+		// the template only sees the attributes kept on spans, as redacted
+		templateFields := fields
+		templateFields.Attributes = t.filterAttributes(fields.Attributes)
+
+		name, err := t.serviceName.execute(templateFields)
+		switch {
+		case err != nil:
+			t.logger.Debug("failed to render service name", zap.Error(err))
+		case name != "":
+			serviceName = name
+		}
+	}
+
+	attrs := [][2]string{{string(semconv.ServiceNameKey), serviceName}}
+
+	// the node the event was reported by, so resources are split per node
+	for _, attr := range [][2]string{
+		{string(semconv.ServiceNamespaceKey), fields.Platform},
+		{string(semconv.ServiceInstanceIDKey), fields.NodeID},
+		{string(semconv.HostNameKey), fields.Hostname},
+		{attributeProcessGroupPath, fields.ProcessGroupPath},
+	} {
+		if attr[1] != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	for _, key := range t.resourceKeys {
+		if key == attributeProcessGroupPath {
+			// always part of the resource
+			continue
+		}
+
+		var value string
+		if field, ok := resourceKeyFields[key]; ok {
+			value = field(fields)
+		} else {
+			// attributes dropped by the filter are left out of the resource as well
+			attr := strings.TrimPrefix(key, resourceKeyAttributesPrefix)
+			if !t.attributeFilter.keep(attr) {
+				continue
+			}
+			value = t.attributeFilter.redactValue(attr, fields.Attributes[attr])
+		}
+
+		if value != "" {
+			attrs = append(attrs, [2]string{key, value})
+		}
+	}
+
+	var sb strings.Builder
+	for _, attr := range attrs {
+		sb.WriteString(attr[0])
+		sb.WriteByte('=')
+		sb.WriteString(attr[1])
+		sb.WriteByte(0)
+	}
+	return resource{key: sb.String(), attributes: attrs}
+}
+
+// filterAttributes returns the flowfile attributes kept by the attribute filter, redacted
+func (t *eventTranslator) filterAttributes(attrs map[string]string) map[string]string {
+	if t.attributeFilter == nil || len(attrs) == 0 {
+		return attrs
+	}
+
+	filtered := make(map[string]string, len(attrs))
+	for key, value := range attrs {
+		if t.attributeFilter.keep(key) {
+			filtered[key] = t.attributeFilter.redactValue(key, value)
+		}
+	}
+	return filtered
+}
+
+// putAttributes sets the attributes of the resource
+func (r resource) putAttributes(attrs pcommon.Map) {
+	for _, attr := range r.attributes {
+		attrs.PutStr(attr[0], attr[1])
+	}
+}
+
+// spanGroups groups spans by resource
+type spanGroups struct {
+	spans     map[string]ptrace.SpanSlice
+	resources map[string]resource
+}
+
+func newSpanGroups() *spanGroups {
+	return &spanGroups{
+		spans:     make(map[string]ptrace.SpanSlice),
+		resources: make(map[string]resource),
+	}
+}
+
+// slice returns the spans of the resource
+func (g *spanGroups) slice(res resource) ptrace.SpanSlice {
+	slice, ok := g.spans[res.key]
+	if !ok {
+		slice = ptrace.NewSpanSlice()
+		g.spans[res.key] = slice
+		g.resources[res.key] = res
+	}
+	return slice
+}
+
+// traces returns a ResourceSpans per resource holding spans
+func (g *spanGroups) traces() ptrace.Traces {
+	results := ptrace.NewTraces()
+	for key, spans := range g.spans {
+		if spans.Len() == 0 {
+			continue
+		}
+
+		rs := results.ResourceSpans().AppendEmpty()
+		rs.SetSchemaUrl(semconv.SchemaURL)
+		g.resources[key].putAttributes(rs.Resource().Attributes())
+
+		in := rs.ScopeSpans().AppendEmpty()
+		setScopeInfo(in.Scope())
+		spans.CopyTo(in.Spans())
+	}
+	return results
+}
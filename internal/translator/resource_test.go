@@ -0,0 +1,165 @@
+package translator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestServiceNameTemplate(t *testing.T) {
+	event := ProvenanceEvent{
+		ProcessGroupName:  "acme-orders",
+		ComponentType:     "PutS3Object",
+		Platform:          "nifi",
+		Application:       "NiFi Flow",
+		UpdatedAttributes: map[string]string{"tenant": "acme"},
+	}
+	bulletin := BulletinEvent{
+		BulletinGroupName: "orders",
+		BulletinGroupPath: "NiFi Flow / tenants / acme / orders",
+	}
+
+	tests := []struct {
+		template string
+		fields   ResourceFields
+		expected string
+	}{
+		{template: "{{ .RootProcessGroup }}", fields: provenanceResourceFields(event), expected: "NiFi Flow"},
+		{template: "{{ .Platform }}-{{ .ComponentType }}", fields: provenanceResourceFields(event), expected: "nifi-PutS3Object"},
+		{template: "{{ .Attributes.tenant }}", fields: provenanceResourceFields(event), expected: "acme"},
+		{template: `{{ index .Attributes "region" | default .Platform }}`, fields: provenanceResourceFields(event), expected: "nifi"},
+		{template: "{{ pathPrefix .ProcessGroupPath 3 }}", fields: bulletinResourceFields(bulletin), expected: "NiFi Flow / tenants / acme"},
+		{template: "{{ .RootProcessGroup }}", fields: bulletinResourceFields(bulletin), expected: "NiFi Flow"},
+		// an empty service name falls back to the process group name
+		{template: "{{ .Attributes.tenant }}", fields: bulletinResourceFields(bulletin), expected: "orders"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := ParseServiceNameTemplate(tt.template)
+			require.NoError(t, err)
+
+			et := NewEventTranslator(zap.NewNop(), Settings{ServiceName: tmpl}).(*eventTranslator)
+			res := et.getResource(tt.fields)
+			assert.Equal(t, [2]string{"service.name", tt.expected}, res.attributes[0])
+		})
+	}
+
+	_, err := ParseServiceNameTemplate("{{ .Platform")
+	assert.Error(t, err)
+}
+
+func TestServiceNameTemplateFilteredAttributes(t *testing.T) {
+	tmpl, err := ParseServiceNameTemplate(`{{ index .Attributes "token" | default "none" }}/{{ index .Attributes "tenant" }}`)
+	require.NoError(t, err)
+
+	filter, err := NewAttributeFilter(AttributeFilterSettings{
+		Exclude: []string{"token"},
+		Redact:  []RedactionRule{{Keys: []string{"tenant"}, Action: RedactionMask}},
+	})
+	require.NoError(t, err)
+
+	// excluded and redacted attributes don't leak into service.name
+	et := NewEventTranslator(zap.NewNop(), Settings{ServiceName: tmpl, Attributes: filter}).(*eventTranslator)
+	res := et.getResource(ResourceFields{Attributes: map[string]string{"token": "s3cr3t", "tenant": "acme"}})
+	assert.Equal(t, [2]string{"service.name", "none/****"}, res.attributes[0])
+}
+
+func TestTranslateProvenanceEventsResourceKeys(t *testing.T) {
+	tmpl, err := ParseServiceNameTemplate("{{ .RootProcessGroup }}")
+	require.NoError(t, err)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{
+		ServiceName:  tmpl,
+		ResourceKeys: []string{"nifi.hostname", "nifi.attributes.tenant"},
+	})
+
+	traces := tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_split.json")))
+	require.Equal(t, 1, traces.ResourceSpans().Len())
+	assert.Equal(t, map[string]any{
+		"service.name":      "NiFi Flow",
+		"service.namespace": "nifi",
+		"host.name":         "nifi-0.nifi.svc",
+		"nifi.hostname":     "nifi-0.nifi.svc",
+	}, traces.ResourceSpans().At(0).Resource().Attributes().AsRaw())
+
+	// events of the same service are split by the values of the resource keys
+	events := loadLineage(t, "synthetic_split.json")
+	events[0].UpdatedAttributes["tenant"] = "acme"
+	traces = tracesOf(et.TranslateProvenanceEvents(events))
+	assert.Equal(t, 2, traces.ResourceSpans().Len())
+
+	// attributes dropped by the filter don't split resources
+	filter, err := NewAttributeFilter(AttributeFilterSettings{Exclude: []string{"tenant"}})
+	require.NoError(t, err)
+	filtered := NewEventTranslator(zap.NewNop(), Settings{
+		ServiceName:  tmpl,
+		ResourceKeys: []string{"nifi.hostname", "nifi.attributes.tenant"},
+		Attributes:   filter,
+	})
+	traces = tracesOf(filtered.TranslateProvenanceEvents(events))
+	require.Equal(t, 1, traces.ResourceSpans().Len())
+	assert.NotContains(t, traces.ResourceSpans().At(0).Resource().Attributes().AsRaw(), "nifi.attributes.tenant")
+
+	metrics := et.TranslateProvenanceEventsToMetrics(loadLineage(t, "synthetic_merge.json"))
+	require.Equal(t, 1, metrics.ResourceMetrics().Len())
+	assert.Equal(t, map[string]any{
+		"service.name":      "NiFi Flow",
+		"service.namespace": "nifi",
+		"host.name":         "nifi-1.nifi.svc",
+		"nifi.hostname":     "nifi-1.nifi.svc",
+	}, metrics.ResourceMetrics().At(0).Resource().Attributes().AsRaw())
+}
+
+func TestTranslateBulletinEventsToLogsResourcePerNode(t *testing.T) {
+	et := NewEventTranslator(zap.NewNop(), Settings{})
+	bulletin := func(id int64, nodeID, nodeAddress string) BulletinEvent {
+		return BulletinEvent{
+			Platform:            "nifi",
+			BulletinId:          id,
+			BulletinGroupName:   "orders",
+			BulletinGroupPath:   "NiFi Flow / orders",
+			BulletinNodeId:      nodeID,
+			BulletinNodeAddress: nodeAddress,
+			BulletinTimestamp:   "2024-04-05T10:00:00.000Z",
+		}
+	}
+
+	logs := et.TranslateBulletinEventsToLogs([]BulletinEvent{
+		bulletin(1, "8c1f0e8a-0d55-4f4b-9e7a-1f2e3d4c5b6a", "nifi-0.nifi.svc"),
+		bulletin(2, "3a9b7c5d-1e2f-4a6b-8c0d-9e8f7a6b5c4d", "nifi-1.nifi.svc"),
+		bulletin(3, "8c1f0e8a-0d55-4f4b-9e7a-1f2e3d4c5b6a", "nifi-0.nifi.svc"),
+	})
+
+	// the same process group is split per node
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+	byHost := make(map[string]map[string]any)
+	for i := 0; i < logs.ResourceLogs().Len(); i++ {
+		attrs := logs.ResourceLogs().At(i).Resource().Attributes().AsRaw()
+		byHost[attrs["host.name"].(string)] = attrs
+	}
+
+	assert.Equal(t, map[string]any{
+		"service.name":            "orders",
+		"service.namespace":       "nifi",
+		"service.instance.id":     "8c1f0e8a-0d55-4f4b-9e7a-1f2e3d4c5b6a",
+		"host.name":               "nifi-0.nifi.svc",
+		"nifi.process_group.path": "NiFi Flow / orders",
+	}, byHost["nifi-0.nifi.svc"])
+	assert.Equal(t, "3a9b7c5d-1e2f-4a6b-8c0d-9e8f7a6b5c4d", byHost["nifi-1.nifi.svc"]["service.instance.id"])
+
+	// the path is also kept on each bulletin
+	record := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, "NiFi Flow / orders", record.Attributes().AsRaw()["nifi.bulletin.group.path"])
+}
+
+func TestValidateResourceKey(t *testing.T) {
+	assert.NoError(t, ValidateResourceKey("nifi.process.group.root"))
+	assert.NoError(t, ValidateResourceKey("nifi.process_group.path"))
+	assert.Error(t, ValidateResourceKey("nifi.process.group.path"))
+	assert.NoError(t, ValidateResourceKey("nifi.attributes.tenant"))
+	assert.Error(t, ValidateResourceKey("nifi.attributes."))
+	assert.Error(t, ValidateResourceKey("host.name"))
+}
@@ -0,0 +1,91 @@
+package translator
+
+import (
+	"github.com/google/uuid"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startLineage tracks a new flowfile with a synthesized root span
+func (t *eventTranslator) startLineage(entityID string, parent trace.SpanContext, entryComponent string, startMillis int64) trace.SpanContext {
+	entry := newLineageEntry(entityID, parent, entryComponent, startMillis)
+	t.spanContextTracking.Set(entityID, entry)
+	return entry.SpanContext
+}
+
+// newLineageEntry returns the entry of a flowfile with a synthesized root span, the root span
+// is a child of parent when valid and the flowfile's events are children of the root span
+func newLineageEntry(entityID string, parent trace.SpanContext, entryComponent string, startMillis int64) SpanContextEntry {
+	traceID := parent.TraceID()
+	if !parent.IsValid() {
+		traceID = trace.TraceID(uuidToTraceID(entityID))
+	}
+
+	rootSpanID := uuidToRootSpanID(entityID)
+	rootCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  rootSpanID,
+	})
+
+	return SpanContextEntry{
+		SpanContext:      rootCtx,
+		RootSpanID:       rootSpanID,
+		RootParentSpanID: parent.SpanID(),
+		Lineage: LineageSummary{
+			EntryComponent: entryComponent,
+			StartMillis:    startMillis,
+		},
+	}
+}
+
+// appendRootSpan appends the flowfile's root span once its lifecycle ends with the event
+func (t *eventTranslator) appendRootSpan(slice ptrace.SpanSlice, event ProvenanceEvent) {
+	// the root span is only emitted once, a redelivered DROP doesn't emit it again
+	var entry SpanContextEntry
+	t.spanContextTracking.Update(event.EntityId, func(tracked *SpanContextEntry) {
+		entry = *tracked
+		tracked.RootSpanID = trace.SpanID{}
+	})
+	if !entry.RootSpanID.IsValid() {
+		return
+	}
+
+	root := slice.AppendEmpty()
+	root.SetKind(ptrace.SpanKindInternal)
+	root.SetTraceID(pcommon.TraceID(entry.SpanContext.TraceID()))
+	root.SetSpanID(pcommon.SpanID(entry.RootSpanID))
+	root.SetParentSpanID(pcommon.SpanID(entry.RootParentSpanID))
+	root.SetName(entry.Lineage.EntryComponent)
+	root.SetStartTimestamp(pcommon.Timestamp(entry.Lineage.StartMillis * 1000000))
+	root.SetEndTimestamp(pcommon.Timestamp(event.TimestampMillis * 1000000))
+
+	root.Attributes().PutStr("nifi.entity.id", event.EntityId)
+	root.Attributes().PutInt("nifi.flowfile.hops", entry.Lineage.Hops)
+	root.Attributes().PutInt("nifi.flowfile.bytes", entry.Lineage.Bytes)
+	root.Attributes().PutStr("nifi.flowfile.disposition", string(event.EventType))
+
+	// forked flowfiles start their own root span but share the lineage of their parent
+	start := entry.Lineage.StartMillis
+	if event.LineageStart > 0 {
+		start = event.LineageStart
+	}
+	root.Attributes().PutInt("nifi.flowfile.lineage.duration", event.TimestampMillis-start)
+}
+
+// lineageStart returns when the event's lineage started, falling back to the event's timestamp
+func lineageStart(event ProvenanceEvent) int64 {
+	if event.LineageStart > 0 {
+		return event.LineageStart
+	}
+	return event.TimestampMillis
+}
+
+// uuidToRootSpanID derives the span id of a flowfile's root span from the last bytes of its
+// uuid, so it doesn't repeat the beginning of the trace id derived from the same uuid
+func uuidToRootSpanID(uuidStr string) trace.SpanID {
+	var spanID trace.SpanID
+	u := uuid.MustParse(uuidStr)
+	copy(spanID[:], u[8:])
+	return spanID
+}
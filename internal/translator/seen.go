@@ -0,0 +1,74 @@
+package translator
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// seenSet remembers keys for a TTL, holding at most maxEntries keys. Keys are
+// kept in insertion order which is also their expiry order, so the oldest key
+// is evicted first when the set is full
+type seenSet struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+type seenEntry struct {
+	key     string
+	expires time.Time
+}
+
+func newSeenSet(ttl time.Duration, maxEntries int) *seenSet {
+	return &seenSet{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// add marks the key as seen, returning false if it was already seen
+func (s *seenSet) add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.expire(now)
+	if _, ok := s.entries[key]; ok {
+		return false
+	}
+
+	if s.order.Len() >= s.maxEntries {
+		s.remove(s.order.Front())
+	}
+
+	s.entries[key] = s.order.PushBack(&seenEntry{key: key, expires: now.Add(s.ttl)})
+	return true
+}
+
+// forget removes the key so it is accepted again
+func (s *seenSet) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+}
+
+func (s *seenSet) expire(now time.Time) {
+	for elem := s.order.Front(); elem != nil && now.After(elem.Value.(*seenEntry).expires); elem = s.order.Front() {
+		s.remove(elem)
+	}
+}
+
+func (s *seenSet) remove(elem *list.Element) {
+	delete(s.entries, elem.Value.(*seenEntry).key)
+	s.order.Remove(elem)
+}
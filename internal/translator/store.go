@@ -0,0 +1,272 @@
+package translator
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanContextStore tracks the span context of each flowfile between translations,
+// implementations must be safe for concurrent use
+type SpanContextStore interface {
+	// Get returns the entry of the flowfile, false if it is unknown or expired
+	Get(flowFileID string) (SpanContextEntry, bool)
+
+	// Set stores the entry of the flowfile and resets its expiry
+	Set(flowFileID string, entry SpanContextEntry)
+
+	// Update atomically applies update to the entry of the flowfile, a zero entry when it is
+	// unknown or expired, then stores it and resets its expiry
+	Update(flowFileID string, update func(entry *SpanContextEntry))
+
+	// Expire removes the entries that were not updated within the store's TTL
+	Expire(now time.Time)
+
+	// Len returns the number of tracked flowfiles
+	Len() int
+
+	// Stats returns the counters of the store since it was created
+	Stats() SpanContextStoreStats
+
+	// Close releases the resources held by the store
+	Close(ctx context.Context) error
+}
+
+// SpanContextEntry is what is tracked for a flowfile
+type SpanContextEntry struct {
+	// SpanContext is the parent of the flowfile's next spans
+	SpanContext trace.SpanContext
+
+	// LastSpan is the flowfile's most recent span, linked from the JOIN events it is a parent of
+	LastSpan trace.SpanContext
+
+	// ComponentSpans is the most recent span of each component that handled the flowfile,
+	// keyed by component id, bulletins are matched to the span of their source
+	ComponentSpans map[string]trace.SpanContext
+
+	// ForkSpan is the FORK or CLONE span that created the flowfile, linked from the flowfile's first span
+	ForkSpan trace.SpanContext
+
+	// ForkEntityID is the flowfile the FORK or CLONE event belongs to
+	ForkEntityID string
+
+	// RootSpanID is the flowfile's synthesized root span, invalid when root spans are
+	// disabled or once the root span was emitted
+	RootSpanID trace.SpanID
+
+	// RootParentSpanID is the parent of the root span, set when the trace was propagated from upstream
+	RootParentSpanID trace.SpanID
+
+	// Lineage summarizes the flowfile's events for its root span
+	Lineage LineageSummary
+}
+
+// LineageSummary accumulates the events of a flowfile
+type LineageSummary struct {
+	// EntryComponent is the name of the component the flowfile entered the flow through
+	EntryComponent string
+
+	// StartMillis is when the flowfile's lifecycle started
+	StartMillis int64
+
+	// Hops is the number of events of the flowfile
+	Hops int64
+
+	// Bytes is the total size of the flowfile over its events
+	Bytes int64
+}
+
+// SpanContextStoreStats are counters used to size the store's TTL and capacity
+type SpanContextStoreStats struct {
+	// Evictions is the number of entries dropped from memory to make room for new ones
+	Evictions int64
+
+	// Expirations is the number of entries removed by Expire once their TTL passed
+	Expirations int64
+
+	// ExpiredLookups is the number of lookups of a flowfile whose entry had already expired
+	ExpiredLookups int64
+}
+
+// lruShards is the number of independently locked shards of the LRU store
+const lruShards = 32
+
+// lruSpanContextStore is an in-memory store bounded to maxEntries, spread over lock-striped
+// shards by flowfile id so concurrent requests rarely contend. Each shard evicts its least
+// recently used entry when full
+type lruSpanContextStore struct {
+	ttl    time.Duration
+	now    func() time.Time
+	shards [lruShards]lruShard
+
+	evictions      atomic.Int64
+	expirations    atomic.Int64
+	expiredLookups atomic.Int64
+}
+
+type lruShard struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type lruEntry struct {
+	key     string
+	entry   SpanContextEntry
+	expires time.Time
+}
+
+var _ SpanContextStore = (*lruSpanContextStore)(nil)
+
+// NewLRUSpanContextStore returns an in-memory store holding at most maxEntries flowfiles for ttl
+func NewLRUSpanContextStore(ttl time.Duration, maxEntries int) SpanContextStore {
+	return newLRUSpanContextStore(ttl, maxEntries)
+}
+
+func newLRUSpanContextStore(ttl time.Duration, maxEntries int) *lruSpanContextStore {
+	s := &lruSpanContextStore{ttl: ttl, now: time.Now}
+	perShard := max(1, (maxEntries+lruShards-1)/lruShards)
+	for i := range s.shards {
+		s.shards[i] = lruShard{
+			maxEntries: perShard,
+			entries:    make(map[string]*list.Element),
+			order:      list.New(),
+		}
+	}
+	return s
+}
+
+func (s *lruSpanContextStore) shard(key string) *lruShard {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &s.shards[h.Sum32()%lruShards]
+}
+
+func (s *lruSpanContextStore) Get(flowFileID string) (SpanContextEntry, bool) {
+	shard := s.shard(flowFileID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	elem, ok := shard.entries[flowFileID]
+	if !ok {
+		return SpanContextEntry{}, false
+	}
+
+	item := elem.Value.(*lruEntry)
+	if s.now().After(item.expires) {
+		shard.remove(elem)
+		s.expiredLookups.Add(1)
+		return SpanContextEntry{}, false
+	}
+
+	shard.order.MoveToFront(elem)
+	return item.entry, true
+}
+
+func (s *lruSpanContextStore) Set(flowFileID string, entry SpanContextEntry) {
+	s.setWithExpiry(flowFileID, entry, s.now().Add(s.ttl))
+}
+
+func (s *lruSpanContextStore) Update(flowFileID string, update func(entry *SpanContextEntry)) {
+	shard := s.shard(flowFileID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	var entry SpanContextEntry
+	now := s.now()
+	if elem, ok := shard.entries[flowFileID]; ok {
+		if item := elem.Value.(*lruEntry); now.After(item.expires) {
+			s.expiredLookups.Add(1)
+		} else {
+			entry = item.entry
+		}
+	}
+
+	update(&entry)
+	s.setLocked(shard, flowFileID, entry, now.Add(s.ttl))
+}
+
+// setWithExpiry stores the entry with an explicit expiry, used when loading persisted entries
+func (s *lruSpanContextStore) setWithExpiry(flowFileID string, entry SpanContextEntry, expires time.Time) {
+	shard := s.shard(flowFileID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	s.setLocked(shard, flowFileID, entry, expires)
+}
+
+// setLocked stores the entry in the shard, whose lock is held
+func (s *lruSpanContextStore) setLocked(shard *lruShard, flowFileID string, entry SpanContextEntry, expires time.Time) {
+	if elem, ok := shard.entries[flowFileID]; ok {
+		item := elem.Value.(*lruEntry)
+		item.entry = entry
+		item.expires = expires
+		shard.order.MoveToFront(elem)
+		return
+	}
+
+	if shard.order.Len() >= shard.maxEntries {
+		shard.remove(shard.order.Back())
+		s.evictions.Add(1)
+	}
+	shard.entries[flowFileID] = shard.order.PushFront(&lruEntry{key: flowFileID, entry: entry, expires: expires})
+}
+
+// delete removes the entry of the flowfile, if any
+func (s *lruSpanContextStore) delete(flowFileID string) {
+	shard := s.shard(flowFileID)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if elem, ok := shard.entries[flowFileID]; ok {
+		shard.remove(elem)
+	}
+}
+
+func (s *lruSpanContextStore) Expire(now time.Time) {
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		for key, elem := range shard.entries {
+			if now.After(elem.Value.(*lruEntry).expires) {
+				shard.order.Remove(elem)
+				delete(shard.entries, key)
+				s.expirations.Add(1)
+			}
+		}
+		shard.mu.Unlock()
+	}
+}
+
+func (s *lruSpanContextStore) Len() int {
+	n := 0
+	for i := range s.shards {
+		shard := &s.shards[i]
+		shard.mu.Lock()
+		n += len(shard.entries)
+		shard.mu.Unlock()
+	}
+	return n
+}
+
+func (s *lruSpanContextStore) Stats() SpanContextStoreStats {
+	return SpanContextStoreStats{
+		Evictions:      s.evictions.Load(),
+		Expirations:    s.expirations.Load(),
+		ExpiredLookups: s.expiredLookups.Load(),
+	}
+}
+
+func (s *lruSpanContextStore) Close(context.Context) error {
+	return nil
+}
+
+func (shard *lruShard) remove(elem *list.Element) {
+	delete(shard.entries, elem.Value.(*lruEntry).key)
+	shard.order.Remove(elem)
+}
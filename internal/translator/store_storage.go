@@ -0,0 +1,576 @@
+package translator
+
+import (
+	"cmp"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	storageManifestKey      = "span_context_segments"
+	storageSegmentKey       = "span_context_segment_"
+	storageSpanContextKey   = "span_context_"
+	storageOperationTimeout = 5 * time.Second
+
+	// storageFlushSize is the number of pending entries that triggers a flush before the interval
+	storageFlushSize = 1000
+
+	// storageFlushInterval is the interval between two writes of the pending entries
+	storageFlushInterval = time.Second
+)
+
+// storageSpanContextStore persists the entries with a storage extension so flowfiles keep
+// their trace across restarts, recently used entries are cached in an LRU store.
+// Entries are written behind by a background goroutine, every storageFlushInterval or once
+// storageFlushSize entries are pending, so neither the storage nor its latency are on the
+// path of the events. The storage extension can't list its keys so each write also stores
+// a segment with the expiry of the entries it wrote, the segments are deleted once all
+// their entries expired and are read back on restart to rebuild the index
+type storageSpanContextStore struct {
+	logger     *zap.Logger
+	client     storage.Client
+	cache      *lruSpanContextStore
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	// updates serializes the updates of each flowfile, striped by flowfile id
+	updates [lruShards]sync.Mutex
+
+	mu sync.Mutex
+	// index holds the expiry of the persisted entries, ordered by expiry so the entries that
+	// expire first, also the least recently updated, are at the front
+	index map[string]*list.Element
+	order *list.List
+	// pending are the entries not written yet and flushing the ones being written, keyed
+	// by flowfile id
+	pending  map[string]pendingSpanContext
+	flushing map[string]pendingSpanContext
+
+	// flushMu serializes the writes, it guards the segments
+	flushMu     sync.Mutex
+	segments    []storageSegment
+	nextSegment uint64
+
+	flushes chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	evictions      atomic.Int64
+	expirations    atomic.Int64
+	expiredLookups atomic.Int64
+}
+
+type indexEntry struct {
+	key     string
+	expires int64
+}
+
+// pendingSpanContext is an entry to write, or to delete when data is nil
+type pendingSpanContext struct {
+	data []byte
+	// expires is the expiry of the entry in unix milliseconds
+	expires int64
+}
+
+// storageSegment is a written segment and the latest expiry of the entries it lists
+type storageSegment struct {
+	id      uint64
+	expires int64
+}
+
+// storageManifest is the range of the segments that may still list unexpired entries
+type storageManifest struct {
+	First uint64 `json:"first"`
+	Next  uint64 `json:"next"`
+}
+
+// storedSpanContext is the persisted form of a SpanContextEntry
+type storedSpanContext struct {
+	TraceID    string `json:"traceId"`
+	SpanID     string `json:"spanId,omitempty"`
+	TraceFlags byte   `json:"traceFlags,omitempty"`
+
+	LastSpan       string            `json:"lastSpan,omitempty"`
+	ComponentSpans map[string]string `json:"componentSpans,omitempty"`
+	ForkSpan       string            `json:"forkSpan,omitempty"`
+	ForkEntityID   string            `json:"forkEntityId,omitempty"`
+
+	RootSpanID       string          `json:"rootSpanId,omitempty"`
+	RootParentSpanID string          `json:"rootParentSpanId,omitempty"`
+	Lineage          *LineageSummary `json:"lineage,omitempty"`
+
+	// Expires is the expiry of the entry in unix milliseconds
+	Expires int64 `json:"expires"`
+}
+
+var _ SpanContextStore = (*storageSpanContextStore)(nil)
+
+// NewStorageSpanContextStore returns a store persisted with a storage extension client,
+// holding at most maxEntries flowfiles for ttl
+func NewStorageSpanContextStore(ctx context.Context, logger *zap.Logger, client storage.Client, ttl time.Duration, maxEntries int) (SpanContextStore, error) {
+	s, err := newStorageSpanContextStore(ctx, logger, client, ttl, maxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	s.done = make(chan struct{})
+	go s.run()
+	return s, nil
+}
+
+// newStorageSpanContextStore returns a store whose pending entries are only written by flush
+func newStorageSpanContextStore(ctx context.Context, logger *zap.Logger, client storage.Client, ttl time.Duration, maxEntries int) (*storageSpanContextStore, error) {
+	s := &storageSpanContextStore{
+		logger:     logger,
+		client:     client,
+		cache:      newLRUSpanContextStore(ttl, maxEntries),
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		index:      make(map[string]*list.Element),
+		order:      list.New(),
+		pending:    make(map[string]pendingSpanContext),
+		flushes:    make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+	}
+
+	if err := s.loadIndex(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// loadIndex rebuilds the index from the segments, the entries expired meanwhile are deleted
+func (s *storageSpanContextStore) loadIndex(ctx context.Context) error {
+	data, err := s.client.Get(ctx, storageManifestKey)
+	if err != nil {
+		return fmt.Errorf("failed to load span context segments: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var manifest storageManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to decode span context segments: %w", err)
+	}
+	s.nextSegment = manifest.Next
+
+	// later segments override the expiry listed by earlier ones, negative for deleted entries
+	expiries := make(map[string]int64)
+	for id := manifest.First; id < manifest.Next; id++ {
+		data, err := s.client.Get(ctx, segmentKey(id))
+		if err != nil {
+			return fmt.Errorf("failed to load span context segment %d: %w", id, err)
+		}
+		if data == nil {
+			continue
+		}
+
+		var segment map[string]int64
+		if err := json.Unmarshal(data, &segment); err != nil {
+			return fmt.Errorf("failed to decode span context segment %d: %w", id, err)
+		}
+
+		latest := int64(0)
+		for key, expires := range segment {
+			expiries[key] = expires
+			latest = max(latest, expires, -expires)
+		}
+		s.segments = append(s.segments, storageSegment{id: id, expires: latest})
+	}
+
+	now := s.now().UnixMilli()
+	entries := make([]indexEntry, 0, len(expiries))
+	for key, expires := range expiries {
+		switch {
+		case expires < 0:
+		case expires < now:
+			s.pending[key] = pendingSpanContext{expires: expires}
+			s.expirations.Add(1)
+		default:
+			entries = append(entries, indexEntry{key: key, expires: expires})
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b indexEntry) int { return cmp.Compare(a.expires, b.expires) })
+	for _, entry := range entries {
+		s.indexLocked(entry.key, entry.expires)
+	}
+	return nil
+}
+
+// run writes the pending entries in the background until the store is closed
+func (s *storageSpanContextStore) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(storageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.flushes:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), storageOperationTimeout)
+		if err := s.flush(ctx); err != nil {
+			s.logger.Warn("failed to persist span contexts", zap.Error(err))
+		}
+		cancel()
+	}
+}
+
+func (s *storageSpanContextStore) Get(flowFileID string) (SpanContextEntry, bool) {
+	if entry, ok := s.cache.Get(flowFileID); ok {
+		return entry, true
+	}
+
+	data, err := s.load(flowFileID)
+	if err != nil || data == nil {
+		if err != nil {
+			s.logger.Warn("failed to load span context", zap.String("flowfile.id", flowFileID), zap.Error(err))
+		}
+		return SpanContextEntry{}, false
+	}
+
+	var stored storedSpanContext
+	if err := json.Unmarshal(data, &stored); err != nil {
+		s.logger.Warn("failed to decode span context", zap.String("flowfile.id", flowFileID), zap.Error(err))
+		return SpanContextEntry{}, false
+	}
+
+	expires := time.UnixMilli(stored.Expires)
+	if s.now().After(expires) {
+		s.expiredLookups.Add(1)
+		return SpanContextEntry{}, false
+	}
+
+	entry, err := stored.entry()
+	if err != nil {
+		s.logger.Warn("failed to decode span context", zap.String("flowfile.id", flowFileID), zap.Error(err))
+		return SpanContextEntry{}, false
+	}
+
+	s.cache.setWithExpiry(flowFileID, entry, expires)
+	return entry, true
+}
+
+func (s *storageSpanContextStore) Update(flowFileID string, update func(entry *SpanContextEntry)) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(flowFileID))
+	mu := &s.updates[h.Sum32()%lruShards]
+	mu.Lock()
+	defer mu.Unlock()
+
+	entry, _ := s.Get(flowFileID)
+	update(&entry)
+	s.Set(flowFileID, entry)
+}
+
+// load returns the encoded entry of the flowfile, the storage is only read for the indexed
+// flowfiles whose entry was written
+func (s *storageSpanContextStore) load(flowFileID string) ([]byte, error) {
+	s.mu.Lock()
+	entry, pending := s.pending[flowFileID]
+	if !pending {
+		entry, pending = s.flushing[flowFileID]
+	}
+	_, indexed := s.index[flowFileID]
+	s.mu.Unlock()
+
+	if pending || !indexed {
+		return entry.data, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storageOperationTimeout)
+	defer cancel()
+	return s.client.Get(ctx, storageSpanContextKey+flowFileID)
+}
+
+func (s *storageSpanContextStore) Set(flowFileID string, entry SpanContextEntry) {
+	expires := s.now().Add(s.ttl)
+	s.cache.setWithExpiry(flowFileID, entry, expires)
+
+	data, err := json.Marshal(newStoredSpanContext(entry, expires))
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.pending[flowFileID] = pendingSpanContext{data: data, expires: expires.UnixMilli()}
+	evicted := s.indexLocked(flowFileID, expires.UnixMilli())
+	full := len(s.pending) >= storageFlushSize
+	s.mu.Unlock()
+
+	for _, key := range evicted {
+		s.cache.delete(key)
+	}
+
+	if full {
+		select {
+		case s.flushes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// indexLocked indexes the flowfile, evicting the entries that expire first past maxEntries.
+// It returns the evicted flowfiles, s.mu is held
+func (s *storageSpanContextStore) indexLocked(flowFileID string, expires int64) []string {
+	if elem, ok := s.index[flowFileID]; ok {
+		elem.Value.(*indexEntry).expires = expires
+		s.order.MoveToBack(elem)
+		return nil
+	}
+
+	var evicted []string
+	for s.order.Len() >= s.maxEntries {
+		entry := s.removeLocked(s.order.Front())
+		evicted = append(evicted, entry.key)
+		s.evictions.Add(1)
+	}
+	s.index[flowFileID] = s.order.PushBack(&indexEntry{key: flowFileID, expires: expires})
+	return evicted
+}
+
+// removeLocked removes the entry from the index and deletes it with the next flush, s.mu is held
+func (s *storageSpanContextStore) removeLocked(elem *list.Element) *indexEntry {
+	entry := s.order.Remove(elem).(*indexEntry)
+	delete(s.index, entry.key)
+	s.pending[entry.key] = pendingSpanContext{expires: entry.expires}
+	return entry
+}
+
+// newStoredSpanContext returns the persisted form of the entry
+func newStoredSpanContext(entry SpanContextEntry, expires time.Time) storedSpanContext {
+	stored := storedSpanContext{
+		TraceID:      entry.SpanContext.TraceID().String(),
+		SpanID:       entry.SpanContext.SpanID().String(),
+		TraceFlags:   byte(entry.SpanContext.TraceFlags()),
+		LastSpan:     encodeSpanContext(entry.LastSpan),
+		ForkSpan:     encodeSpanContext(entry.ForkSpan),
+		ForkEntityID: entry.ForkEntityID,
+		Expires:      expires.UnixMilli(),
+	}
+	for componentID, spanCtx := range entry.ComponentSpans {
+		if stored.ComponentSpans == nil {
+			stored.ComponentSpans = make(map[string]string, len(entry.ComponentSpans))
+		}
+		stored.ComponentSpans[componentID] = encodeSpanContext(spanCtx)
+	}
+	if entry.RootSpanID.IsValid() {
+		stored.RootSpanID = entry.RootSpanID.String()
+		stored.RootParentSpanID = entry.RootParentSpanID.String()
+	}
+	if entry.Lineage != (LineageSummary{}) {
+		stored.Lineage = &entry.Lineage
+	}
+	return stored
+}
+
+// Expire removes the expired entries from the cache and the index, they are deleted from
+// the storage with the next flush
+func (s *storageSpanContextStore) Expire(now time.Time) {
+	s.cache.Expire(now)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for elem := s.order.Front(); elem != nil && now.UnixMilli() > elem.Value.(*indexEntry).expires; elem = s.order.Front() {
+		s.removeLocked(elem)
+		s.expirations.Add(1)
+	}
+}
+
+func (s *storageSpanContextStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index)
+}
+
+// Stats returns the counters of the store, evictions include the entries evicted from the
+// in-memory cache and the ones removed from the storage to stay within maxEntries
+func (s *storageSpanContextStore) Stats() SpanContextStoreStats {
+	return SpanContextStoreStats{
+		Evictions:      s.evictions.Load() + s.cache.Stats().Evictions,
+		Expirations:    s.expirations.Load(),
+		ExpiredLookups: s.expiredLookups.Load(),
+	}
+}
+
+func (s *storageSpanContextStore) Close(ctx context.Context) error {
+	close(s.stop)
+	if s.done != nil {
+		<-s.done
+	}
+
+	if err := s.flush(ctx); err != nil {
+		return err
+	}
+	return s.client.Close(ctx)
+}
+
+// flush writes the pending entries in a single batch, along with a segment listing their
+// expiry. The pending entries are swapped for a snapshot so the storage is written without
+// holding s.mu, they are pending again on failure unless they were updated meanwhile
+func (s *storageSpanContextStore) flush(ctx context.Context) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	now := s.now().UnixMilli()
+	live := 0
+	for live < len(s.segments) && s.segments[live].expires < now {
+		live++
+	}
+
+	s.mu.Lock()
+	snapshot := s.pending
+	if len(snapshot) == 0 && live == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	s.pending = make(map[string]pendingSpanContext)
+	s.flushing = snapshot
+	s.mu.Unlock()
+
+	ops := make([]storage.Operation, 0, len(snapshot)+live+2)
+	segment := make(map[string]int64, len(snapshot))
+	latest := int64(0)
+	for key, entry := range snapshot {
+		if entry.data == nil {
+			ops = append(ops, storage.DeleteOperation(storageSpanContextKey+key))
+			segment[key] = -entry.expires
+		} else {
+			ops = append(ops, storage.SetOperation(storageSpanContextKey+key, entry.data))
+			segment[key] = entry.expires
+		}
+		latest = max(latest, entry.expires)
+	}
+
+	segments := s.segments[live:]
+	nextSegment := s.nextSegment
+	if latest >= now {
+		data, err := json.Marshal(segment)
+		if err != nil {
+			return s.restore(snapshot, err)
+		}
+		ops = append(ops, storage.SetOperation(segmentKey(nextSegment), data))
+		segments = append(segments, storageSegment{id: nextSegment, expires: latest})
+		nextSegment++
+	}
+	for _, expired := range s.segments[:live] {
+		ops = append(ops, storage.DeleteOperation(segmentKey(expired.id)))
+	}
+
+	manifest := storageManifest{First: nextSegment, Next: nextSegment}
+	if len(segments) > 0 {
+		manifest.First = segments[0].id
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return s.restore(snapshot, err)
+	}
+	ops = append(ops, storage.SetOperation(storageManifestKey, data))
+
+	if err := s.client.Batch(ctx, ops...); err != nil {
+		return s.restore(snapshot, err)
+	}
+
+	s.segments = segments
+	s.nextSegment = nextSegment
+	s.mu.Lock()
+	s.flushing = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// restore makes the snapshot of a failed flush pending again, except for the entries
+// updated since, and returns err
+func (s *storageSpanContextStore) restore(snapshot map[string]pendingSpanContext, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, entry := range snapshot {
+		if _, ok := s.pending[key]; !ok {
+			s.pending[key] = entry
+		}
+	}
+	s.flushing = nil
+	return err
+}
+
+// segmentKey returns the storage key of a segment
+func segmentKey(id uint64) string {
+	return fmt.Sprintf("%s%d", storageSegmentKey, id)
+}
+
+func (stored storedSpanContext) entry() (SpanContextEntry, error) {
+	traceID, err := trace.TraceIDFromHex(stored.TraceID)
+	if err != nil {
+		return SpanContextEntry{}, err
+	}
+
+	// flowfiles tracked before their first span have no span id
+	spanID, _ := trace.SpanIDFromHex(stored.SpanID)
+	entry := SpanContextEntry{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.TraceFlags(stored.TraceFlags),
+		}),
+		LastSpan:     decodeSpanContext(stored.LastSpan),
+		ForkSpan:     decodeSpanContext(stored.ForkSpan),
+		ForkEntityID: stored.ForkEntityID,
+	}
+
+	for componentID, spanCtx := range stored.ComponentSpans {
+		if entry.ComponentSpans == nil {
+			entry.ComponentSpans = make(map[string]trace.SpanContext, len(stored.ComponentSpans))
+		}
+		entry.ComponentSpans[componentID] = decodeSpanContext(spanCtx)
+	}
+	if stored.RootSpanID != "" {
+		entry.RootSpanID, _ = trace.SpanIDFromHex(stored.RootSpanID)
+		entry.RootParentSpanID, _ = trace.SpanIDFromHex(stored.RootParentSpanID)
+	}
+	if stored.Lineage != nil {
+		entry.Lineage = *stored.Lineage
+	}
+	return entry, nil
+}
+
+// encodeSpanContext encodes the trace and span ids of a span context, empty when invalid
+func encodeSpanContext(spanCtx trace.SpanContext) string {
+	if !spanCtx.IsValid() {
+		return ""
+	}
+	return spanCtx.TraceID().String() + "-" + spanCtx.SpanID().String()
+}
+
+// decodeSpanContext decodes a span context encoded by encodeSpanContext
+func decodeSpanContext(value string) trace.SpanContext {
+	traceHex, spanHex, ok := strings.Cut(value, "-")
+	if !ok {
+		return trace.SpanContext{}
+	}
+
+	traceID, _ := trace.TraceIDFromHex(traceHex)
+	spanID, _ := trace.SpanIDFromHex(spanHex)
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+}
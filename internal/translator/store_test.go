@@ -0,0 +1,258 @@
+package translator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// mapClient is an in-memory storage client
+type mapClient map[string][]byte
+
+func (m mapClient) Get(_ context.Context, key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m mapClient) Set(_ context.Context, key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapClient) Delete(_ context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m mapClient) Batch(_ context.Context, ops ...storage.Operation) error {
+	for _, op := range ops {
+		switch op.Type {
+		case storage.Get:
+			op.Value = m[op.Key]
+		case storage.Set:
+			m[op.Key] = op.Value
+		case storage.Delete:
+			delete(m, op.Key)
+		}
+	}
+	return nil
+}
+
+func (m mapClient) Close(context.Context) error {
+	return nil
+}
+
+func newTestEntry() SpanContextEntry {
+	spanCtx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID(uuidToTraceID("1b6a4a7e-2a4e-4a4e-9f0e-6d0a6d4f1c11")),
+		SpanID:  trace.SpanID(uuidToSpanID("5e0a3c6e-9a1f-4c1e-8c64-0c1a3b8d2e22")),
+	})
+	return SpanContextEntry{
+		SpanContext:    spanCtx,
+		LastSpan:       spanCtx,
+		ComponentSpans: map[string]trace.SpanContext{"0b6e3f54-018e-1000-6a1d-3c25f2a11e0a": spanCtx},
+		ForkSpan:       spanCtx,
+		ForkEntityID:   "1b6a4a7e-2a4e-4a4e-9f0e-6d0a6d4f1c11",
+		RootSpanID:     uuidToRootSpanID("1b6a4a7e-2a4e-4a4e-9f0e-6d0a6d4f1c11"),
+		Lineage:        LineageSummary{EntryComponent: "GenerateFlowFile", StartMillis: 1000, Hops: 2, Bytes: 10},
+	}
+}
+
+func TestLRUSpanContextStore(t *testing.T) {
+	now := time.Now()
+	store := newLRUSpanContextStore(time.Minute, lruShards)
+	store.now = func() time.Time { return now }
+	entry := newTestEntry()
+
+	// each shard holds a single entry, find two keys sharing a shard
+	keys := []string{"a"}
+	for c := 'b'; len(keys) < 2; c++ {
+		if store.shard(string(c)) == store.shard("a") {
+			keys = append(keys, string(c))
+		}
+	}
+
+	store.Set(keys[0], entry)
+	got, ok := store.Get(keys[0])
+	require.True(t, ok)
+	assert.Equal(t, entry, got)
+
+	store.Set(keys[1], entry)
+	_, ok = store.Get(keys[0])
+	assert.False(t, ok, "the least recently used entry should be evicted")
+
+	now = now.Add(2 * time.Minute)
+	_, ok = store.Get(keys[1])
+	assert.False(t, ok, "expired entries should not be returned")
+
+	store.Set("b", entry)
+	store.Expire(now.Add(2 * time.Minute))
+	assert.Zero(t, store.Len())
+	assert.Equal(t, SpanContextStoreStats{Evictions: 1, Expirations: 1, ExpiredLookups: 1}, store.Stats())
+}
+
+func TestStorageSpanContextStore(t *testing.T) {
+	ctx := context.Background()
+	client := mapClient{}
+	entry := newTestEntry()
+
+	store, err := NewStorageSpanContextStore(ctx, zap.NewNop(), client, time.Hour, 10)
+	require.NoError(t, err)
+	store.Set("flowfile", entry)
+	require.NoError(t, store.Close(ctx))
+
+	// a new store simulates a restart, the entry is loaded from the storage
+	store, err = NewStorageSpanContextStore(ctx, zap.NewNop(), client, time.Hour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.Len())
+
+	got, ok := store.Get("flowfile")
+	require.True(t, ok)
+	assert.Equal(t, entry, got)
+
+	store.Expire(time.Now().Add(2 * time.Hour))
+	assert.Zero(t, store.Len())
+	require.NoError(t, store.Close(ctx))
+	assert.NotContains(t, client, storageSpanContextKey+"flowfile")
+}
+
+func TestStorageSpanContextStoreWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	client := mapClient{}
+	entry := newTestEntry()
+	now := time.Now()
+
+	store, err := newStorageSpanContextStore(ctx, zap.NewNop(), client, time.Hour, 10)
+	require.NoError(t, err)
+	store.now = func() time.Time { return now }
+
+	// entries are pending until flushed in the background
+	store.Set("flowfile", entry)
+	assert.Empty(t, client)
+	got, ok := store.Get("flowfile")
+	require.True(t, ok)
+	assert.Equal(t, entry, got)
+
+	require.NoError(t, store.flush(ctx))
+	assert.Contains(t, client, storageSpanContextKey+"flowfile")
+	assert.Contains(t, client, segmentKey(0))
+	assert.Contains(t, client, storageManifestKey)
+
+	// each flush only writes the entries updated since the previous one
+	now = now.Add(time.Minute)
+	store.Set("other", entry)
+	require.NoError(t, store.flush(ctx))
+	assert.JSONEq(t, `{"other": `+fmt.Sprint(now.Add(time.Hour).UnixMilli())+`}`, string(client[segmentKey(1)]))
+
+	// expired entries and the segments listing them are deleted by the next flush
+	now = now.Add(2 * time.Hour)
+	store.Expire(now)
+	assert.Zero(t, store.Len())
+	require.NoError(t, store.flush(ctx))
+	assert.Len(t, client, 1)
+	assert.Contains(t, client, storageManifestKey)
+}
+
+func TestStorageSpanContextStoreCapacity(t *testing.T) {
+	ctx := context.Background()
+	client := mapClient{}
+	entry := newTestEntry()
+
+	store, err := newStorageSpanContextStore(ctx, zap.NewNop(), client, time.Hour, 2)
+	require.NoError(t, err)
+
+	store.Set("a", entry)
+	require.NoError(t, store.flush(ctx))
+	store.Set("b", entry)
+	store.Set("c", entry)
+	assert.Equal(t, 2, store.Len())
+	assert.Equal(t, int64(1), store.Stats().Evictions)
+
+	_, ok := store.Get("a")
+	assert.False(t, ok, "the entry expiring first should be evicted")
+	require.NoError(t, store.Close(ctx))
+	assert.NotContains(t, client, storageSpanContextKey+"a")
+
+	// the eviction is recorded in the segments so a restart doesn't index the entry again
+	store, err = newStorageSpanContextStore(ctx, zap.NewNop(), client, time.Hour, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.Len())
+	_, ok = store.Get("b")
+	assert.True(t, ok)
+	require.NoError(t, store.Close(ctx))
+}
+
+func TestStorageSpanContextStoreFlushFailure(t *testing.T) {
+	ctx := context.Background()
+	client := &failingClient{mapClient: mapClient{}}
+	entry := newTestEntry()
+
+	store, err := newStorageSpanContextStore(ctx, zap.NewNop(), client, time.Hour, 10)
+	require.NoError(t, err)
+
+	store.Set("flowfile", entry)
+	client.fail = true
+	require.Error(t, store.flush(ctx))
+
+	// the entry stays readable and is written by the next flush
+	_, ok := store.Get("flowfile")
+	assert.True(t, ok)
+	client.fail = false
+	require.NoError(t, store.flush(ctx))
+	assert.Contains(t, client.mapClient, storageSpanContextKey+"flowfile")
+}
+
+// failingClient is a storage client whose batches fail while fail is set
+type failingClient struct {
+	mapClient
+	fail bool
+}
+
+func (c *failingClient) Batch(ctx context.Context, ops ...storage.Operation) error {
+	if c.fail {
+		return errors.New("storage unavailable")
+	}
+	return c.mapClient.Batch(ctx, ops...)
+}
+
+func TestSpanContextStoreUpdate(t *testing.T) {
+	lru := NewLRUSpanContextStore(time.Minute, 10)
+	persisted, err := newStorageSpanContextStore(context.Background(), zap.NewNop(), mapClient{}, time.Minute, 10)
+	require.NoError(t, err)
+	defer persisted.Close(context.Background())
+
+	for name, store := range map[string]SpanContextStore{"lru": lru, "storage": persisted} {
+		t.Run(name, func(t *testing.T) {
+			// concurrent updates of the same flowfile don't overwrite each other
+			var wg sync.WaitGroup
+			for i := 0; i < 50; i++ {
+				wg.Add(1)
+				go func(component string) {
+					defer wg.Done()
+					store.Update("flowfile", func(entry *SpanContextEntry) {
+						components := maps.Clone(entry.ComponentSpans)
+						if components == nil {
+							components = make(map[string]trace.SpanContext)
+						}
+						components[component] = newTestEntry().SpanContext
+						entry.ComponentSpans = components
+					})
+				}(fmt.Sprintf("component-%d", i))
+			}
+			wg.Wait()
+
+			entry, ok := store.Get("flowfile")
+			require.True(t, ok)
+			assert.Len(t, entry.ComponentSpans, 50)
+		})
+	}
+}
@@ -2,64 +2,195 @@ package translator
 
 import (
 	"fmt"
-	"runtime/debug"
 	"slices"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 type EventTranslator interface {
-	// TranslateProvenanceEvents translates a slice of ProvenanceEvent into a ptrace.Traces
-	TranslateProvenanceEvents(events []ProvenanceEvent) ptrace.Traces
+	// TranslateProvenanceEvents translates a slice of ProvenanceEvent into a ptrace.Traces, along with
+	// the held bulletins it released, see HoldBulletins
+	TranslateProvenanceEvents(events []ProvenanceEvent) (ptrace.Traces, []BulletinEvent)
+
+	// TranslateProvenanceEventsToMetrics aggregates a slice of ProvenanceEvent into a pmetric.Metrics
+	TranslateProvenanceEventsToMetrics(events []ProvenanceEvent) pmetric.Metrics
 
 	// TranslateBulletinEvents translates a slice of BulletinEvent into a ptrace.Traces
 	TranslateBulletinEvents(events []BulletinEvent) ptrace.Traces
 
-	// Cleanup cleans up the translator
-	Cleanup()
+	// TranslateBulletinEventsToLogs translates a slice of BulletinEvent into a plog.Logs
+	TranslateBulletinEventsToLogs(events []BulletinEvent) plog.Logs
+
+	// DeduplicateProvenanceEvents drops the provenance events already seen by the signal's pipeline,
+	// e.g. redelivered by NiFi
+	DeduplicateProvenanceEvents(signal component.DataType, events []ProvenanceEvent) []ProvenanceEvent
+
+	// DeduplicateBulletinEvents drops the bulletin events already seen by the signal's pipeline,
+	// e.g. redelivered by NiFi
+	DeduplicateBulletinEvents(signal component.DataType, events []BulletinEvent) []BulletinEvent
+
+	// ForgetProvenanceEvents forgets the provenance events were seen by the signal's pipeline,
+	// used when they failed to be consumed
+	ForgetProvenanceEvents(signal component.DataType, events []ProvenanceEvent)
+
+	// ForgetBulletinEvents forgets the bulletin events were seen by the signal's pipeline,
+	// used when they failed to be consumed
+	ForgetBulletinEvents(signal component.DataType, events []BulletinEvent)
+
+	// ReleaseExpiredBulletins translates the bulletins held past the hold timeout into a ptrace.Traces,
+	// along with the released bulletins, see HoldBulletins
+	ReleaseExpiredBulletins(now time.Time) (ptrace.Traces, []BulletinEvent)
+
+	// HoldBulletins returns released bulletins to the hold, used when their traces failed to be consumed
+	HoldBulletins(events []BulletinEvent)
 }
 
-type spanContextTracking struct {
-	spanContext trace.SpanContext
-	ttl         time.Time
+// Settings configures the event translator
+type Settings struct {
+	IgnoredEventTypes         []ProvenanceEventType
+	ContextPropagationAliases map[string]string
+
+	// DeduplicationTTL is how long seen events are remembered, 0 disables deduplication
+	DeduplicationTTL time.Duration
+
+	// DeduplicationMaxEntries bounds the number of events remembered per signal
+	DeduplicationMaxEntries int
+
+	// SpanContextStore tracks the span context of flowfiles, defaults to an in-memory LRU store
+	SpanContextStore SpanContextStore
+
+	// ChainEvents makes each event's span the parent of the next event of the same flowfile,
+	// instead of parenting all of them to the span of the CREATE or RECEIVE event
+	ChainEvents bool
+
+	// RootSpans synthesizes a root span per flowfile covering its whole lifecycle,
+	// emitted once the flowfile is dropped or expired
+	RootSpans bool
+
+	// ForkStrategy decides the trace of forked and cloned flowfiles, defaults to ForkSameTrace
+	ForkStrategy ForkStrategy
+
+	// JoinStrategy decides the trace of merged flowfiles, defaults to JoinNewTraceLinked
+	JoinStrategy JoinStrategy
+
+	// ServiceName derives service.name from the events, defaults to the process group name
+	ServiceName *ServiceNameTemplate
+
+	// ResourceKeys are added to the resource along with service.name, events are grouped
+	// by the values of all of them, see ValidateResourceKey
+	ResourceKeys []string
+
+	// Attributes filters and redacts flowfile attributes and event fields, nil keeps them as is
+	Attributes *AttributeFilter
+
+	// RecordAttributeChanges records the attributes added, changed or removed by each event
+	// as an attributes.changed span event
+	RecordAttributeChanges bool
+
+	// OmitSpanAttributes keeps the flowfile attributes off the spans' nifi.attributes.* attributes
+	OmitSpanAttributes bool
+
+	// BulletinHoldTimeout is how long the bulletins of flowfiles without provenance events yet
+	// are held for their span, 0 disables the hold
+	BulletinHoldTimeout time.Duration
+
+	// BulletinHoldMaxEntries bounds the number of held bulletins, bulletins are translated
+	// right away when the hold is full
+	BulletinHoldMaxEntries int
 }
 
+const (
+	defaultSpanContextTTL        = 5 * time.Minute
+	defaultSpanContextMaxEntries = 100000
+)
+
 type eventTranslator struct {
 	logger            *zap.Logger
 	ignoredEventTypes map[ProvenanceEventType]bool
 
 	// Keep track of the span context for each event.EntityId
-	spanContextTracking       map[string]spanContextTracking
+	spanContextTracking       SpanContextStore
 	contextPropagationAliases map[string]string
+	chainEvents               bool
+	rootSpans                 bool
+	forkStrategy              ForkStrategy
+	joinStrategy              JoinStrategy
+	serviceName               *ServiceNameTemplate
+	resourceKeys              []string
+	attributeFilter           *AttributeFilter
+	recordAttributeChanges    bool
+	omitSpanAttributes        bool
+
+	// The end of the last data point of each metric series
+	metricSeries *metricSeries
+
+	// Bulletins waiting for their flowfile's span, nil when the hold is disabled
+	bulletinHold *bulletinHold
+
+	// Events seen recently by each signal's pipeline, nil when deduplication is disabled
+	seenProvenance map[component.DataType]*seenSet
+	seenBulletins  map[component.DataType]*seenSet
 }
 
-func NewEventTranslator(
-	logger *zap.Logger,
-	ignoredEventTypes []ProvenanceEventType,
-	contextPropagationAliases map[string]string,
-) EventTranslator {
+func NewEventTranslator(logger *zap.Logger, settings Settings) EventTranslator {
 	ignoredEventsMap := make(map[ProvenanceEventType]bool)
-	for _, eventType := range ignoredEventTypes {
+	for _, eventType := range settings.IgnoredEventTypes {
 		ignoredEventsMap[eventType] = true
 	}
 
-	return &eventTranslator{
+	store := settings.SpanContextStore
+	if store == nil {
+		store = NewLRUSpanContextStore(defaultSpanContextTTL, defaultSpanContextMaxEntries)
+	}
+
+	t := &eventTranslator{
 		logger:                    logger,
 		ignoredEventTypes:         ignoredEventsMap,
-		spanContextTracking:       make(map[string]spanContextTracking),
-		contextPropagationAliases: contextPropagationAliases,
+		spanContextTracking:       store,
+		contextPropagationAliases: settings.ContextPropagationAliases,
+		chainEvents:               settings.ChainEvents,
+		rootSpans:                 settings.RootSpans,
+		forkStrategy:              settings.ForkStrategy,
+		joinStrategy:              settings.JoinStrategy,
+		serviceName:               settings.ServiceName,
+		resourceKeys:              settings.ResourceKeys,
+		attributeFilter:           settings.Attributes,
+		recordAttributeChanges:    settings.RecordAttributeChanges,
+		omitSpanAttributes:        settings.OmitSpanAttributes,
+		metricSeries:              newMetricSeries(),
+	}
+
+	if settings.DeduplicationTTL > 0 {
+		t.seenProvenance = map[component.DataType]*seenSet{
+			component.DataTypeTraces:  newSeenSet(settings.DeduplicationTTL, settings.DeduplicationMaxEntries),
+			component.DataTypeMetrics: newSeenSet(settings.DeduplicationTTL, settings.DeduplicationMaxEntries),
+		}
+		t.seenBulletins = map[component.DataType]*seenSet{
+			component.DataTypeTraces: newSeenSet(settings.DeduplicationTTL, settings.DeduplicationMaxEntries),
+			component.DataTypeLogs:   newSeenSet(settings.DeduplicationTTL, settings.DeduplicationMaxEntries),
+		}
 	}
+
+	if settings.BulletinHoldTimeout > 0 {
+		t.bulletinHold = newBulletinHold(settings.BulletinHoldTimeout, settings.BulletinHoldMaxEntries)
+	}
+	return t
 }
 
-// TranslateProvenanceEvents translates a slice of ProvenanceEvent into a ptrace.Traces
-func (t *eventTranslator) TranslateProvenanceEvents(events []ProvenanceEvent) ptrace.Traces {
-	groupByService := make(map[string]ptrace.SpanSlice)
+// TranslateProvenanceEvents translates a slice of ProvenanceEvent into a ptrace.Traces, along with
+// the held bulletins of their flowfiles it released
+func (t *eventTranslator) TranslateProvenanceEvents(events []ProvenanceEvent) (ptrace.Traces, []BulletinEvent) {
+	groups := newSpanGroups()
+	released := make(map[string][]BulletinEvent)
+	var taken []BulletinEvent
 	slices.SortFunc(events, func(a ProvenanceEvent, b ProvenanceEvent) int {
 		return int(a.EventOrdinal) - int(b.EventOrdinal)
 	})
@@ -70,12 +201,7 @@ func (t *eventTranslator) TranslateProvenanceEvents(events []ProvenanceEvent) pt
 		}
 
 		kind := t.getSpanKind(event)
-		serviceName := t.getServiceName(event)
-		slice, exist := groupByService[serviceName]
-		if !exist {
-			slice = ptrace.NewSpanSlice()
-			groupByService[serviceName] = slice
-		}
+		slice := groups.slice(t.getResource(provenanceResourceFields(event)))
 
 		spanCtx := t.getSpanContext(event)
 		newSpan := slice.AppendEmpty()
@@ -83,6 +209,7 @@ func (t *eventTranslator) TranslateProvenanceEvents(events []ProvenanceEvent) pt
 		newSpan.SetTraceID(pcommon.TraceID(spanCtx.TraceID()))
 		newSpan.SetParentSpanID(pcommon.SpanID(spanCtx.SpanID()))
 		newSpan.SetSpanID(uuidToSpanID(event.EventId))
+		t.trackSpan(event, newSpan)
 
 		newSpan.SetName(fmt.Sprintf("%s %s", event.ComponentName, event.EventType))
 		newSpan.SetEndTimestamp(pcommon.Timestamp(event.TimestampMillis * 1000000))
@@ -92,7 +219,7 @@ func (t *eventTranslator) TranslateProvenanceEvents(events []ProvenanceEvent) pt
 
 		newSpan.Attributes().PutStr("nifi.event.id", event.EventId)
 		newSpan.Attributes().PutStr("nifi.event.type", string(event.EventType))
-		newSpan.Attributes().PutStr("nifi.event.details", event.Details)
+		newSpan.Attributes().PutStr("nifi.event.details", t.attributeFilter.redactValue(redactionKeyDetails, event.Details))
 		newSpan.Attributes().PutStr("nifi.component.id", event.ComponentId)
 		newSpan.Attributes().PutStr("nifi.component.type", event.ComponentType)
 		newSpan.Attributes().PutStr("nifi.component.name", event.ComponentName)
@@ -105,148 +232,83 @@ func (t *eventTranslator) TranslateProvenanceEvents(events []ProvenanceEvent) pt
 		newSpan.Attributes().PutStr("nifi.platform", event.Platform)
 		newSpan.Attributes().PutStr("nifi.application", event.Application)
 
-		for key, val := range event.UpdatedAttributes {
-			newSpan.
-				Attributes().
-				PutStr(fmt.Sprintf("nifi.attributes.%s", strings.ToLower(key)), val)
+		if hasTransitUri(event) {
+			putTransitAttributes(newSpan.Attributes(), event.TransitUri)
+			t.redactTransitUri(newSpan.Attributes(), event.TransitUri)
 		}
 
-		if event.EventType == ProvenanceEventTypeJoin {
-			// Add links to the parent spans, only unique links
-			spanCtxs := make(map[trace.TraceID]trace.SpanContext)
-			for _, parent := range event.ParentIds {
-				spanCtx := t.spanContextTracking[parent].spanContext
-				spanCtxs[spanCtx.TraceID()] = spanCtx
-			}
-
-			for _, spanCtx := range spanCtxs {
-				ln := newSpan.Links().AppendEmpty()
-				ln.SetTraceID(pcommon.TraceID(spanCtx.TraceID()))
+		if !t.omitSpanAttributes {
+			for key, val := range event.UpdatedAttributes {
+				if !t.attributeFilter.keep(key) {
+					continue
+				}
+				newSpan.
+					Attributes().
+					PutStr(fmt.Sprintf("nifi.attributes.%s", strings.ToLower(key)), t.attributeFilter.redactValue(key, val))
 			}
 		}
-	}
 
-	results := ptrace.NewTraces()
-	for service, spans := range groupByService {
-		rs := results.ResourceSpans().AppendEmpty()
-		rs.SetSchemaUrl(semconv.SchemaURL)
-		rs.Resource().Attributes().PutStr(string(semconv.ServiceNameKey), service)
+		if t.recordAttributeChanges {
+			t.appendAttributeChanges(newSpan, event)
+		}
 
-		in := rs.ScopeSpans().AppendEmpty()
-		in.Scope().SetName("nifi.provenance.receiver")
+		if event.EventType == ProvenanceEventTypeJoin {
+			t.appendJoinLinks(newSpan, event)
+		}
 
-		info, ok := debug.ReadBuildInfo()
-		if ok {
-			in.Scope().SetVersion(info.Main.Version)
-		} else {
-			in.Scope().SetVersion("unknown")
+		if t.bulletinHold != nil {
+			taken = append(taken, t.attachHeldBulletins(newSpan, event, released)...)
 		}
 
-		spans.CopyTo(in.Spans())
+		if t.rootSpans && (event.EventType == ProvenanceEventTypeDrop || event.EventType == ProvenanceEventTypeExpire) {
+			t.appendRootSpan(slice, event)
+		}
 	}
 
-	return results
+	// held bulletins without a span of their component are correlated to their flowfile
+	for _, bulletins := range released {
+		for _, bulletin := range bulletins {
+			t.appendBulletinSpan(groups.slice(t.getResource(bulletinResourceFields(bulletin))), bulletin)
+		}
+	}
+	return groups.traces(), taken
 }
 
-// TranslateBulletinEvents translates a slice of BulletinEvent into a ptrace.Traces
+// TranslateBulletinEvents translates a slice of BulletinEvent into a ptrace.Traces, when enabled
+// the bulletins of flowfiles without provenance events yet are held until they are translated
 func (t *eventTranslator) TranslateBulletinEvents(events []BulletinEvent) ptrace.Traces {
-	groupByService := make(map[string]ptrace.SpanSlice)
+	groups := newSpanGroups()
 	for _, event := range events {
-		serviceName := event.BulletinGroupName
-		slice, exist := groupByService[serviceName]
-		if !exist {
-			slice = ptrace.NewSpanSlice()
-			groupByService[serviceName] = slice
-		}
-
 		if len(event.BulletinFlowFileUuid) == 0 {
 			t.logger.Warn("received event with empty flowfile uuid", zap.Any("event", event))
 			continue
 		}
 
-		defaultSpanCtx := trace.NewSpanContext(trace.SpanContextConfig{
-			TraceID: trace.TraceID(uuidToTraceID(event.BulletinFlowFileUuid)),
-		})
-
-		ctx, ok := t.spanContextTracking[event.BulletinFlowFileUuid]
-		if !ok {
-			ctx.spanContext = defaultSpanCtx
-		}
-
-		newSpan := slice.AppendEmpty()
-		newSpan.SetKind(ptrace.SpanKindInternal)
-		newSpan.SetSpanID(uuidToSpanID(event.ObjectId))
-		newSpan.SetTraceID(pcommon.TraceID(ctx.spanContext.TraceID()))
-		newSpan.SetParentSpanID(pcommon.SpanID(ctx.spanContext.SpanID()))
-
-		switch strings.ToLower(event.BulletinLevel) {
-		case "error":
-			newSpan.Status().SetCode(ptrace.StatusCodeError)
-		default:
-			newSpan.Status().SetCode(ptrace.StatusCodeUnset)
-		}
-
-		newSpan.SetName(fmt.Sprintf("%s %s", event.BulletinSourceName, event.BulletinLevel))
-
-		ts, err := time.Parse("2006-01-02T15:04:05.999Z", event.BulletinTimestamp)
-		if err != nil {
-			t.logger.Error("failed to parse timestamp for event",
-				zap.String("object.id", event.ObjectId),
-				zap.Int64("bulletin.id", event.BulletinId))
+		if t.holdBulletin(event) {
 			continue
 		}
-
-		newSpan.SetStartTimestamp(pcommon.Timestamp(ts.UnixMilli() * 1000000))
-		newSpan.SetEndTimestamp(
-			pcommon.Timestamp(ts.UnixMilli() * 1000000),
-		) // Bulletin events dont have any duration
-
-		newSpan.Attributes().PutStr("nifi.object.id", event.ObjectId)
-		newSpan.Attributes().PutStr("nifi.platform", event.Platform)
-		newSpan.Attributes().PutInt("nifi.bulletin.id", event.BulletinId)
-		newSpan.Attributes().PutStr("nifi.bulletin.category", event.BulletinCategory)
-		newSpan.Attributes().PutStr("nifi.bulletin.group.id", event.BulletinGroupId)
-		newSpan.Attributes().PutStr("nifi.bulletin.group.name", event.BulletinGroupName)
-		newSpan.Attributes().PutStr("nifi.bulletin.group.path", event.BulletinGroupPath)
-		newSpan.Attributes().PutStr("nifi.bulletin.level", event.BulletinLevel)
-		newSpan.Attributes().PutStr("nifi.bulletin.message", event.BulletinMessage)
-		newSpan.Attributes().PutStr("nifi.bulletin.node.address", event.BulletinNodeAddress)
-		newSpan.Attributes().PutStr("nifi.bulletin.node.id", event.BulletinNodeId)
-		newSpan.Attributes().PutStr("nifi.bulletin.source.id", event.BulletinSourceId)
-		newSpan.Attributes().PutStr("nifi.bulletin.source.name", event.BulletinSourceName)
-		newSpan.Attributes().PutStr("nifi.bulletin.source.type", event.BulletinSourceType)
-		newSpan.Attributes().PutStr("nifi.bulletin.flowfile.id", event.BulletinFlowFileUuid)
+		t.appendBulletinSpan(groups.slice(t.getResource(bulletinResourceFields(event))), event)
 	}
-
-	results := ptrace.NewTraces()
-	for service, spans := range groupByService {
-		rs := results.ResourceSpans().AppendEmpty()
-		rs.SetSchemaUrl(semconv.SchemaURL)
-		rs.Resource().Attributes().PutStr(string(semconv.ServiceNameKey), service)
-
-		in := rs.ScopeSpans().AppendEmpty()
-		in.Scope().SetName("nifi.provenance.receiver")
-
-		info, ok := debug.ReadBuildInfo()
-		if ok {
-			in.Scope().SetVersion(info.Main.Version)
-		} else {
-			in.Scope().SetVersion("unknown")
-		}
-
-		spans.CopyTo(in.Spans())
-	}
-
-	return results
-
+	return groups.traces()
 }
 
-func (t *eventTranslator) Cleanup() {
-	for k := range t.spanContextTracking {
-		if time.Now().After(t.spanContextTracking[k].ttl) {
-			delete(t.spanContextTracking, k)
-		}
-	}
+// putBulletinAttributes sets the nifi.* attributes describing a bulletin event
+func (t *eventTranslator) putBulletinAttributes(attrs pcommon.Map, event BulletinEvent) {
+	attrs.PutStr("nifi.object.id", event.ObjectId)
+	attrs.PutStr("nifi.platform", event.Platform)
+	attrs.PutInt("nifi.bulletin.id", event.BulletinId)
+	attrs.PutStr("nifi.bulletin.category", event.BulletinCategory)
+	attrs.PutStr("nifi.bulletin.group.id", event.BulletinGroupId)
+	attrs.PutStr("nifi.bulletin.group.name", event.BulletinGroupName)
+	attrs.PutStr("nifi.bulletin.group.path", event.BulletinGroupPath)
+	attrs.PutStr("nifi.bulletin.level", event.BulletinLevel)
+	attrs.PutStr("nifi.bulletin.message", t.attributeFilter.redactValue(redactionKeyBulletinMessage, event.BulletinMessage))
+	attrs.PutStr("nifi.bulletin.node.address", event.BulletinNodeAddress)
+	attrs.PutStr("nifi.bulletin.node.id", event.BulletinNodeId)
+	attrs.PutStr("nifi.bulletin.source.id", event.BulletinSourceId)
+	attrs.PutStr("nifi.bulletin.source.name", event.BulletinSourceName)
+	attrs.PutStr("nifi.bulletin.source.type", event.BulletinSourceType)
+	attrs.PutStr("nifi.bulletin.flowfile.id", event.BulletinFlowFileUuid)
 }
 
 // shouldIgnore returns true if the event should be ignored
@@ -255,15 +317,10 @@ func (t *eventTranslator) shouldIgnore(event ProvenanceEvent) bool {
 	return ok
 }
 
-// getServiceName returns the service name for the event
-func (t *eventTranslator) getServiceName(event ProvenanceEvent) string {
-	return event.ProcessGroupName
-}
-
 // getSpanKind returns the span kind for the event
 func (t *eventTranslator) getSpanKind(event ProvenanceEvent) trace.SpanKind {
 	switch event.EventType {
-	case ProvenanceEventTypeSend:
+	case ProvenanceEventTypeSend, ProvenanceEventTypeFetch, ProvenanceEventTypeRemoteInvocation:
 		return trace.SpanKindClient
 	case ProvenanceEventTypeReceive:
 		return trace.SpanKindServer
@@ -277,11 +334,13 @@ func (t *eventTranslator) getSpanContext(event ProvenanceEvent) trace.SpanContex
 	// try to extract the span context from the event
 	if event.EventType == ProvenanceEventTypeCreate ||
 		event.EventType == ProvenanceEventTypeReceive {
-		if spanCtx := extractTraceContext(event.UpdatedAttributes, t.contextPropagationAliases); spanCtx.IsValid() {
-			t.spanContextTracking[event.EntityId] = spanContextTracking{
-				spanContext: spanCtx,
-				ttl:         time.Now().Add(5 * time.Minute),
-			}
+		spanCtx := extractTraceContext(event.UpdatedAttributes, t.contextPropagationAliases)
+		if t.rootSpans {
+			return t.startLineage(event.EntityId, spanCtx, event.ComponentName, lineageStart(event))
+		}
+
+		if spanCtx.IsValid() {
+			t.spanContextTracking.Set(event.EntityId, SpanContextEntry{SpanContext: spanCtx})
 			return spanCtx
 		}
 
@@ -291,10 +350,7 @@ func (t *eventTranslator) getSpanContext(event ProvenanceEvent) trace.SpanContex
 			TraceID:    trace.TraceID(uuidToTraceID(event.EntityId)),
 		})
 
-		t.spanContextTracking[event.EntityId] = spanContextTracking{
-			spanContext: ctx,
-			ttl:         time.Now().Add(5 * time.Minute),
-		}
+		t.spanContextTracking.Set(event.EntityId, SpanContextEntry{SpanContext: ctx})
 
 		return trace.NewSpanContext(trace.SpanContextConfig{
 			TraceID: trace.TraceID(uuidToTraceID(event.EntityId)),
@@ -310,8 +366,8 @@ func (t *eventTranslator) getSpanContext(event ProvenanceEvent) trace.SpanContex
 	if event.EventType == ProvenanceEventTypeFork || event.EventType == ProvenanceEventTypeClone {
 		traceID := uuidToTraceID(event.EntityId)
 		parentSpanID := uuidToSpanID(event.EventId)
-		if ctx, ok := t.spanContextTracking[event.EntityId]; ok {
-			traceID = pcommon.TraceID(ctx.spanContext.TraceID())
+		if ctx, ok := t.spanContextTracking.Get(event.EntityId); ok {
+			traceID = pcommon.TraceID(ctx.SpanContext.TraceID())
 		}
 
 		childSpanCtx := trace.NewSpanContext(trace.SpanContextConfig{
@@ -321,21 +377,38 @@ func (t *eventTranslator) getSpanContext(event ProvenanceEvent) trace.SpanContex
 		})
 
 		for _, childId := range event.ChildIds {
-			t.spanContextTracking[childId] = spanContextTracking{
-				spanContext: childSpanCtx,
-				ttl:         time.Now().Add(5 * time.Minute),
+			parent := t.forkParent(childId, childSpanCtx)
+			entry := SpanContextEntry{SpanContext: parent}
+			if t.rootSpans {
+				// the child's lifecycle starts with the fork
+				entry = newLineageEntry(childId, parent, event.ComponentName, event.TimestampMillis)
 			}
+
+			// the child's first span links back to the fork
+			entry.ForkSpan = childSpanCtx
+			entry.ForkEntityID = event.EntityId
+			t.spanContextTracking.Set(childId, entry)
 		}
+	}
 
-		if ctx, ok := t.spanContextTracking[event.EntityId]; ok {
-			return ctx.spanContext
+	// the merged flowfile continues the trace of one of its parents
+	if event.EventType == ProvenanceEventTypeJoin {
+		if parent, ok := t.adoptedParent(event); ok {
+			if t.rootSpans {
+				return t.startLineage(event.EntityId, parent, event.ComponentName, lineageStart(event))
+			}
+			t.spanContextTracking.Set(event.EntityId, SpanContextEntry{SpanContext: parent})
+			return parent
 		}
+	}
 
-		return defaultSpanCtx
+	if ctx, ok := t.spanContextTracking.Get(event.EntityId); ok {
+		return ctx.SpanContext
 	}
 
-	if ctx, ok := t.spanContextTracking[event.EntityId]; ok {
-		return ctx.spanContext
+	if t.rootSpans {
+		// the flowfile was first seen mid-flow, its lifecycle starts here
+		return t.startLineage(event.EntityId, trace.SpanContext{}, event.ComponentName, lineageStart(event))
 	}
 
 	return defaultSpanCtx
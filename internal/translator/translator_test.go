@@ -0,0 +1,318 @@
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// TestTranslatorConcurrency hammers a single translator from many goroutines,
+// run with -race to detect unsynchronized access to the span context store
+func TestTranslatorConcurrency(t *testing.T) {
+	store := NewLRUSpanContextStore(time.Minute, 1000)
+	et := NewEventTranslator(zap.NewNop(), Settings{SpanContextStore: store})
+	flowfiles := make([]string, 50)
+	for i := range flowfiles {
+		flowfiles[i] = uuid.NewString()
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				entity := flowfiles[i%len(flowfiles)]
+				child := flowfiles[(i+1)%len(flowfiles)]
+				events := []ProvenanceEvent{
+					{EventId: uuid.NewString(), EventOrdinal: 0, EventType: ProvenanceEventTypeCreate, EntityId: entity},
+					{EventId: uuid.NewString(), EventOrdinal: 1, EventType: ProvenanceEventTypeFork, EntityId: entity, ChildIds: []string{child}},
+					{EventId: uuid.NewString(), EventOrdinal: 2, EventType: ProvenanceEventTypeJoin, EntityId: child, ParentIds: []string{entity}},
+					{EventId: uuid.NewString(), EventOrdinal: 3, EventType: ProvenanceEventTypeDrop, EntityId: entity},
+				}
+				bulletins := []BulletinEvent{{
+					ObjectId:             uuid.NewString(),
+					BulletinId:           int64(i),
+					BulletinFlowFileUuid: entity,
+					BulletinTimestamp:    "2024-01-01T00:00:00.000Z",
+					BulletinMessage:      fmt.Sprintf("bulletin %d", i),
+				}}
+
+				assert.Equal(t, 4, tracesOf(et.TranslateProvenanceEvents(events)).SpanCount())
+				et.TranslateProvenanceEventsToMetrics(events)
+				assert.Equal(t, 1, et.TranslateBulletinEvents(bulletins).SpanCount())
+				assert.Equal(t, 1, et.TranslateBulletinEventsToLogs(bulletins).LogRecordCount())
+				store.Expire(time.Now())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// tracesOf returns the traces of a translation, dropping the bulletins it released
+func tracesOf(traces ptrace.Traces, _ []BulletinEvent) ptrace.Traces {
+	return traces
+}
+
+// spansByEventID indexes the spans of the traces by their nifi.event.id attribute
+func spansByEventID(traces ptrace.Traces) map[string]ptrace.Span {
+	spans := make(map[string]ptrace.Span)
+	for i := 0; i < traces.ResourceSpans().Len(); i++ {
+		scopeSpans := traces.ResourceSpans().At(i).ScopeSpans()
+		for j := 0; j < scopeSpans.Len(); j++ {
+			for k := 0; k < scopeSpans.At(j).Spans().Len(); k++ {
+				span := scopeSpans.At(j).Spans().At(k)
+				if id, ok := span.Attributes().Get("nifi.event.id"); ok {
+					spans[id.Str()] = span
+				}
+			}
+		}
+	}
+	return spans
+}
+
+func TestTranslateProvenanceEventsChainEvents(t *testing.T) {
+	entity := uuid.NewString()
+	ids := []string{uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	events := []ProvenanceEvent{
+		{EventId: ids[1], EventOrdinal: 1, EventType: ProvenanceEventTypeContentModified, EntityId: entity},
+		{EventId: ids[0], EventOrdinal: 0, EventType: ProvenanceEventTypeCreate, EntityId: entity},
+		{EventId: ids[2], EventOrdinal: 2, EventType: ProvenanceEventTypeAttributesModified, EntityId: entity},
+	}
+
+	t.Run("chained", func(t *testing.T) {
+		et := NewEventTranslator(zap.NewNop(), Settings{ChainEvents: true})
+		spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(slices.Clone(events))))
+
+		assert.True(t, spans[ids[0]].ParentSpanID().IsEmpty())
+		assert.Equal(t, spans[ids[0]].SpanID(), spans[ids[1]].ParentSpanID())
+		assert.Equal(t, spans[ids[1]].SpanID(), spans[ids[2]].ParentSpanID())
+
+		// the chain continues across batches
+		next := []ProvenanceEvent{{EventId: ids[3], EventOrdinal: 3, EventType: ProvenanceEventTypeDrop, EntityId: entity}}
+		nextSpans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(next)))
+		assert.Equal(t, spans[ids[2]].SpanID(), nextSpans[ids[3]].ParentSpanID())
+		assert.Equal(t, spans[ids[0]].TraceID(), nextSpans[ids[3]].TraceID())
+	})
+
+	t.Run("flat", func(t *testing.T) {
+		et := NewEventTranslator(zap.NewNop(), Settings{})
+		spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(slices.Clone(events))))
+
+		assert.Equal(t, spans[ids[0]].SpanID(), spans[ids[1]].ParentSpanID())
+		assert.Equal(t, spans[ids[0]].SpanID(), spans[ids[2]].ParentSpanID())
+	})
+}
+
+func TestTranslateProvenanceEventsRootSpan(t *testing.T) {
+	entity := uuid.NewString()
+	ids := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	et := NewEventTranslator(zap.NewNop(), Settings{RootSpans: true})
+
+	first := spansByEventID(tracesOf(et.TranslateProvenanceEvents([]ProvenanceEvent{
+		{EventId: ids[0], EventOrdinal: 0, EventType: ProvenanceEventTypeCreate, EntityId: entity,
+			ComponentName: "GenerateFlowFile", TimestampMillis: 1000, LineageStart: 900, EntitySize: 10},
+		{EventId: ids[1], EventOrdinal: 1, EventType: ProvenanceEventTypeContentModified, EntityId: entity,
+			ComponentName: "ReplaceText", TimestampMillis: 2000, EntitySize: 20},
+	})))
+	assert.Len(t, first, 2, "the root span is only emitted once the flowfile is dropped")
+
+	traces := tracesOf(et.TranslateProvenanceEvents([]ProvenanceEvent{
+		{EventId: ids[2], EventOrdinal: 2, EventType: ProvenanceEventTypeDrop, EntityId: entity,
+			ComponentName: "PutFile", TimestampMillis: 3000, LineageStart: 900, EntitySize: 20},
+	}))
+	require.Equal(t, 2, traces.SpanCount())
+
+	var root ptrace.Span
+	spans := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans()
+	for i := 0; i < spans.Len(); i++ {
+		if spans.At(i).Name() == "GenerateFlowFile" {
+			root = spans.At(i)
+		}
+	}
+
+	require.False(t, root.SpanID().IsEmpty(), "root span should be emitted")
+	assert.True(t, root.ParentSpanID().IsEmpty())
+	assert.Equal(t, pcommon.Timestamp(900*1000000), root.StartTimestamp())
+	assert.Equal(t, pcommon.Timestamp(3000*1000000), root.EndTimestamp())
+	assert.Equal(t, map[string]any{
+		"nifi.entity.id":                 entity,
+		"nifi.flowfile.hops":             int64(3),
+		"nifi.flowfile.bytes":            int64(50),
+		"nifi.flowfile.disposition":      "DROP",
+		"nifi.flowfile.lineage.duration": int64(2100),
+	}, root.Attributes().AsRaw())
+
+	last := spansByEventID(traces)
+	for _, span := range []ptrace.Span{first[ids[0]], first[ids[1]], last[ids[2]]} {
+		assert.Equal(t, root.TraceID(), span.TraceID())
+		assert.Equal(t, root.SpanID(), span.ParentSpanID())
+	}
+}
+
+func TestTranslateProvenanceEventsLineageLinks(t *testing.T) {
+	parents := []string{uuid.NewString(), uuid.NewString()}
+	child, merged := uuid.NewString(), uuid.NewString()
+	ids := []string{uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	et := NewEventTranslator(zap.NewNop(), Settings{})
+
+	spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents([]ProvenanceEvent{
+		{EventId: ids[0], EventOrdinal: 0, EventType: ProvenanceEventTypeCreate, EntityId: parents[0]},
+		{EventId: ids[1], EventOrdinal: 1, EventType: ProvenanceEventTypeCreate, EntityId: parents[1]},
+		{EventId: ids[2], EventOrdinal: 2, EventType: ProvenanceEventTypeFork, EntityId: parents[0], ChildIds: []string{child}},
+		{EventId: ids[3], EventOrdinal: 3, EventType: ProvenanceEventTypeAttributesModified, EntityId: child},
+		{EventId: ids[4], EventOrdinal: 4, EventType: ProvenanceEventTypeJoin, EntityId: merged, ParentIds: parents},
+	})))
+
+	// the forked child's first span links back to the fork
+	forkLinks := spans[ids[3]].Links()
+	require.Equal(t, 1, forkLinks.Len())
+	assert.Equal(t, spans[ids[2]].SpanID(), forkLinks.At(0).SpanID())
+	assert.Equal(t, spans[ids[2]].TraceID(), forkLinks.At(0).TraceID())
+	assert.Equal(t, map[string]any{"nifi.entity.id": parents[0]}, forkLinks.At(0).Attributes().AsRaw())
+
+	// the join links to the last span of each parent
+	joinLinks := spans[ids[4]].Links()
+	require.Equal(t, 2, joinLinks.Len())
+	assert.Equal(t, spans[ids[2]].SpanID(), joinLinks.At(0).SpanID())
+	assert.Equal(t, map[string]any{"nifi.entity.id": parents[0]}, joinLinks.At(0).Attributes().AsRaw())
+	assert.Equal(t, spans[ids[1]].SpanID(), joinLinks.At(1).SpanID())
+	assert.Equal(t, spans[ids[1]].TraceID(), joinLinks.At(1).TraceID())
+	assert.Equal(t, map[string]any{"nifi.entity.id": parents[1]}, joinLinks.At(1).Attributes().AsRaw())
+}
+
+// loadLineage loads the provenance events of a fixture under testdata/lineage. The fixtures
+// are hand-written to follow the reporting task's JSON format, not captured from a flow
+func loadLineage(t *testing.T, name string) []ProvenanceEvent {
+	data, err := os.ReadFile(filepath.Join("testdata", "lineage", name))
+	require.NoError(t, err)
+
+	var events []ProvenanceEvent
+	require.NoError(t, json.Unmarshal(data, &events))
+	return events
+}
+
+func TestTranslateProvenanceEventsForkStrategy(t *testing.T) {
+	const (
+		fork   = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a02"
+		send   = "6f1c2a80-4b1e-4d0e-9a51-0d5e8c1f7a04"
+		child  = "c7d1e9f0-2a3b-4c5d-9e8f-0a1b2c3d4e01"
+		parent = "a3f0c5d2-7e1b-4f6a-8c2d-1b9e4d7f3a10"
+	)
+
+	tests := []struct {
+		name       string
+		strategy   ForkStrategy
+		traceID    func(spans map[string]ptrace.Span) pcommon.TraceID
+		parentSpan func(spans map[string]ptrace.Span) pcommon.SpanID
+	}{
+		{
+			name:       "default",
+			traceID:    func(spans map[string]ptrace.Span) pcommon.TraceID { return spans[fork].TraceID() },
+			parentSpan: func(spans map[string]ptrace.Span) pcommon.SpanID { return spans[fork].SpanID() },
+		},
+		{
+			name:       "same trace",
+			strategy:   ForkSameTrace,
+			traceID:    func(spans map[string]ptrace.Span) pcommon.TraceID { return spans[fork].TraceID() },
+			parentSpan: func(spans map[string]ptrace.Span) pcommon.SpanID { return spans[fork].SpanID() },
+		},
+		{
+			name:       "new trace linked",
+			strategy:   ForkNewTraceLinked,
+			traceID:    func(map[string]ptrace.Span) pcommon.TraceID { return uuidToTraceID(child) },
+			parentSpan: func(map[string]ptrace.Span) pcommon.SpanID { return pcommon.NewSpanIDEmpty() },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			et := NewEventTranslator(zap.NewNop(), Settings{ForkStrategy: tt.strategy})
+			spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_split.json"))))
+
+			assert.Equal(t, tt.traceID(spans), spans[send].TraceID())
+			assert.Equal(t, tt.parentSpan(spans), spans[send].ParentSpanID())
+
+			// the child's first span links back to the fork either way
+			links := spans[send].Links()
+			require.Equal(t, 1, links.Len())
+			assert.Equal(t, spans[fork].TraceID(), links.At(0).TraceID())
+			assert.Equal(t, spans[fork].SpanID(), links.At(0).SpanID())
+			assert.Equal(t, map[string]any{"nifi.entity.id": parent}, links.At(0).Attributes().AsRaw())
+		})
+	}
+}
+
+func TestTranslateProvenanceEventsJoinStrategy(t *testing.T) {
+	const (
+		// the first parent's last event
+		firstParentLast = "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b03"
+		// the second parent's last event, its lineage started first
+		oldestParentLast = "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b02"
+		join             = "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b04"
+		send             = "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b05"
+		merged           = "f9b1d3e5-7a8c-4e0f-a2b4-c6d8e0f2a401"
+	)
+
+	tests := []struct {
+		name     string
+		strategy JoinStrategy
+		adopted  string
+	}{
+		{name: "default"},
+		{name: "new trace linked", strategy: JoinNewTraceLinked},
+		{name: "adopt first parent", strategy: JoinAdoptFirstParent, adopted: firstParentLast},
+		{name: "adopt oldest lineage", strategy: JoinAdoptOldestLineage, adopted: oldestParentLast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			et := NewEventTranslator(zap.NewNop(), Settings{JoinStrategy: tt.strategy})
+			spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_merge.json"))))
+
+			if tt.adopted == "" {
+				assert.Equal(t, uuidToTraceID(merged), spans[join].TraceID())
+				assert.True(t, spans[join].ParentSpanID().IsEmpty())
+			} else {
+				assert.Equal(t, spans[tt.adopted].TraceID(), spans[join].TraceID())
+				assert.Equal(t, spans[tt.adopted].SpanID(), spans[join].ParentSpanID())
+			}
+
+			// the merged flowfile's next spans stay in the JOIN span's trace
+			assert.Equal(t, spans[join].TraceID(), spans[send].TraceID())
+
+			// the JOIN span links to every parent either way
+			links := spans[join].Links()
+			require.Equal(t, 2, links.Len())
+			assert.Equal(t, spans[firstParentLast].SpanID(), links.At(0).SpanID())
+			assert.Equal(t, spans[oldestParentLast].SpanID(), links.At(1).SpanID())
+		})
+	}
+}
+
+func TestTranslateProvenanceEventsJoinStrategyRootSpan(t *testing.T) {
+	const (
+		oldestParentLast = "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b02"
+		join             = "2d8e4b61-9c0a-4f3e-b7d2-5a6c1e0f9b04"
+		merged           = "f9b1d3e5-7a8c-4e0f-a2b4-c6d8e0f2a401"
+	)
+
+	et := NewEventTranslator(zap.NewNop(), Settings{JoinStrategy: JoinAdoptOldestLineage, RootSpans: true})
+	spans := spansByEventID(tracesOf(et.TranslateProvenanceEvents(loadLineage(t, "synthetic_merge.json"))))
+
+	// the merged flowfile's root span is a child of the adopted parent's last span
+	assert.Equal(t, spans[oldestParentLast].TraceID(), spans[join].TraceID())
+	assert.Equal(t, pcommon.SpanID(uuidToRootSpanID(merged)), spans[join].ParentSpanID())
+}
@@ -2,6 +2,7 @@ package translator
 
 import (
 	"context"
+	"runtime/debug"
 
 	"github.com/google/uuid"
 	"go.opentelemetry.io/collector/pdata/pcommon"
@@ -34,3 +35,15 @@ func extractTraceContext(attrs, aliases map[string]string) trace.SpanContext {
 	ctx := tc.Extract(context.Background(), newCaseInsensitiveMapCarrier(attrs, aliases))
 	return trace.SpanContextFromContext(ctx)
 }
+
+// setScopeInfo sets the name and version of the receiver's instrumentation scope
+func setScopeInfo(scope pcommon.InstrumentationScope) {
+	scope.SetName("nifi.provenance.receiver")
+
+	info, ok := debug.ReadBuildInfo()
+	if ok {
+		scope.SetVersion(info.Main.Version)
+	} else {
+		scope.SetVersion("unknown")
+	}
+}
@@ -0,0 +1,250 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	pendingDir    = "pending"
+	deadLetterDir = "dead-letter"
+	corruptDir    = "corrupt"
+	segmentExt    = ".json"
+)
+
+// dirLog stores each record as a segment file, pending segments are moved to the
+// dead-letter directory when rejected so they can be inspected with regular tools.
+// Segments that can't be decoded are moved to the corrupt directory
+type dirLog struct {
+	logger *zap.Logger
+	mu     sync.Mutex
+	dir    string
+	nextID uint64
+}
+
+var _ Log = (*dirLog)(nil)
+
+// NewDirLog returns a log persisted as segment files under dir
+func NewDirLog(logger *zap.Logger, dir string) (Log, error) {
+	l := &dirLog{logger: logger, dir: dir}
+	for _, sub := range []string{pendingDir, deadLetterDir, corruptDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create wal directory: %w", err)
+		}
+
+		ids, err := l.list(sub)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > 0 {
+			l.nextID = max(l.nextID, ids[len(ids)-1]+1)
+		}
+	}
+	return l, nil
+}
+
+func (l *dirLog) segmentPath(sub string, id uint64) string {
+	return filepath.Join(l.dir, sub, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+func (l *dirLog) Append(_ context.Context, record Record) (uint64, error) {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	id := l.nextID
+	l.nextID++
+	l.mu.Unlock()
+
+	if err := writeFileSync(l.segmentPath(pendingDir, id), data); err != nil {
+		return 0, fmt.Errorf("failed to persist wal segment: %w", err)
+	}
+	return id, nil
+}
+
+func (l *dirLog) Remove(_ context.Context, id uint64) error {
+	err := os.Remove(l.segmentPath(pendingDir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (l *dirLog) DeadLetter(_ context.Context, id uint64, reason string) error {
+	path := l.segmentPath(pendingDir, id)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	record, err := decodeRecord(data)
+	if err != nil {
+		return err
+	}
+
+	record.Reason = reason
+	if data, err = encodeRecord(record); err != nil {
+		return err
+	}
+
+	if err := writeFileSync(l.segmentPath(deadLetterDir, id), data); err != nil {
+		return fmt.Errorf("failed to persist dead-letter segment: %w", err)
+	}
+	return os.Remove(path)
+}
+
+func (l *dirLog) Pending(_ context.Context, from uint64, limit int) ([]Entry, error) {
+	ids, err := l.list(pendingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	start, _ := slices.BinarySearch(ids, from)
+	entries := make([]Entry, 0, min(limit, len(ids)-start))
+	for _, id := range ids[start:] {
+		if len(entries) == limit {
+			break
+		}
+
+		data, err := os.ReadFile(l.segmentPath(pendingDir, id))
+		if errors.Is(err, os.ErrNotExist) {
+			// consumed meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := decodeRecord(data)
+		if err != nil {
+			l.quarantine(id, err)
+			continue
+		}
+		entries = append(entries, Entry{ID: id, Record: record})
+	}
+	return entries, nil
+}
+
+// quarantine moves a pending segment that can't be decoded to the corrupt directory
+func (l *dirLog) quarantine(id uint64, err error) {
+	path := l.segmentPath(corruptDir, id)
+	l.logger.Error("Moving corrupt wal segment out of the pending batches",
+		zap.Uint64("id", id), zap.String("path", path), zap.Error(err))
+
+	if err := os.Rename(l.segmentPath(pendingDir, id), path); err != nil {
+		l.logger.Error("Failed to move corrupt wal segment", zap.Uint64("id", id), zap.Error(err))
+		return
+	}
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		l.logger.Warn("Failed to sync wal directory", zap.Error(err))
+	}
+}
+
+func (l *dirLog) DeadLetters(context.Context) ([]Entry, error) {
+	return l.load(deadLetterDir)
+}
+
+func (l *dirLog) Close(context.Context) error {
+	return nil
+}
+
+// list returns the ids of the segments in the sub directory, in ascending order
+func (l *dirLog) list(sub string) ([]uint64, error) {
+	files, err := os.ReadDir(filepath.Join(l.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uint64
+	for _, file := range files {
+		name, ok := strings.CutSuffix(file.Name(), segmentExt)
+		if !ok || file.IsDir() {
+			continue
+		}
+
+		id, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids, nil
+}
+
+func (l *dirLog) load(sub string) ([]Entry, error) {
+	ids, err := l.list(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		data, err := os.ReadFile(l.segmentPath(sub, id))
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := decodeRecord(data)
+		if err != nil {
+			l.logger.Error("Skipping corrupt wal segment", zap.Uint64("id", id), zap.String("dir", sub), zap.Error(err))
+			continue
+		}
+		entries = append(entries, Entry{ID: id, Record: record})
+	}
+	return entries, nil
+}
+
+// writeFileSync atomically writes data to path, the file is synced to disk before being renamed
+// and its directory after, so the rename itself survives a crash
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir syncs the entries of the directory to disk
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,201 @@
+package wal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+)
+
+const storageIndexKey = "wal_index"
+
+// storageIndex tracks the records held in the storage extension, which can't list its keys
+type storageIndex struct {
+	NextID      uint64   `json:"nextId"`
+	Pending     []uint64 `json:"pending"`
+	DeadLetters []uint64 `json:"deadLetters"`
+
+	// Corrupt are the records that couldn't be decoded, kept for inspection
+	Corrupt []uint64 `json:"corrupt,omitempty"`
+}
+
+type storageLog struct {
+	logger *zap.Logger
+	mu     sync.Mutex
+	client storage.Client
+	index  storageIndex
+}
+
+var _ Log = (*storageLog)(nil)
+
+// NewStorageLog returns a log persisted with a storage extension client
+func NewStorageLog(ctx context.Context, logger *zap.Logger, client storage.Client) (Log, error) {
+	data, err := client.Get(ctx, storageIndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wal index: %w", err)
+	}
+
+	l := &storageLog{logger: logger, client: client}
+	if data != nil {
+		if err := json.Unmarshal(data, &l.index); err != nil {
+			return nil, fmt.Errorf("failed to decode wal index: %w", err)
+		}
+	}
+	return l, nil
+}
+
+func storageRecordKey(id uint64) string {
+	return fmt.Sprintf("wal_record_%d", id)
+}
+
+func (l *storageLog) Append(ctx context.Context, record Record) (uint64, error) {
+	data, err := encodeRecord(record)
+	if err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := l.index
+	id := index.NextID
+	index.NextID++
+	index.Pending = append(slices.Clip(index.Pending), id)
+	if err := l.commit(ctx, index, storage.SetOperation(storageRecordKey(id), data)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (l *storageLog) Remove(ctx context.Context, id uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := l.index
+	index.Pending = slices.DeleteFunc(slices.Clone(index.Pending), func(v uint64) bool { return v == id })
+	return l.commit(ctx, index, storage.DeleteOperation(storageRecordKey(id)))
+}
+
+func (l *storageLog) DeadLetter(ctx context.Context, id uint64, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := l.client.Get(ctx, storageRecordKey(id))
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return fmt.Errorf("wal record %d not found", id)
+	}
+
+	record, err := decodeRecord(data)
+	if err != nil {
+		return err
+	}
+
+	record.Reason = reason
+	if data, err = encodeRecord(record); err != nil {
+		return err
+	}
+
+	index := l.index
+	index.Pending = slices.DeleteFunc(slices.Clone(index.Pending), func(v uint64) bool { return v == id })
+	index.DeadLetters = append(slices.Clip(index.DeadLetters), id)
+	return l.commit(ctx, index, storage.SetOperation(storageRecordKey(id), data))
+}
+
+func (l *storageLog) Pending(ctx context.Context, from uint64, limit int) ([]Entry, error) {
+	// records are read without holding the lock, the ones consumed meanwhile are not found
+	l.mu.Lock()
+	start, _ := slices.BinarySearch(l.index.Pending, from)
+	ids := slices.Clone(l.index.Pending[start:])
+	l.mu.Unlock()
+
+	entries := make([]Entry, 0, min(limit, len(ids)))
+	for _, id := range ids {
+		if len(entries) == limit {
+			break
+		}
+
+		data, err := l.client.Get(ctx, storageRecordKey(id))
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+
+		record, err := decodeRecord(data)
+		if err != nil {
+			l.quarantine(ctx, id, err)
+			continue
+		}
+		entries = append(entries, Entry{ID: id, Record: record})
+	}
+	return entries, nil
+}
+
+// quarantine moves a pending record that can't be decoded to the corrupt records of the index
+func (l *storageLog) quarantine(ctx context.Context, id uint64, err error) {
+	l.logger.Error("Moving corrupt wal record out of the pending batches",
+		zap.Uint64("id", id), zap.String("key", storageRecordKey(id)), zap.Error(err))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	index := l.index
+	index.Pending = slices.DeleteFunc(slices.Clone(index.Pending), func(v uint64) bool { return v == id })
+	index.Corrupt = append(slices.Clip(index.Corrupt), id)
+	if err := l.commit(ctx, index); err != nil {
+		l.logger.Error("Failed to move corrupt wal record", zap.Uint64("id", id), zap.Error(err))
+	}
+}
+
+func (l *storageLog) DeadLetters(ctx context.Context) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load(ctx, l.index.DeadLetters)
+}
+
+func (l *storageLog) Close(ctx context.Context) error {
+	return l.client.Close(ctx)
+}
+
+// commit applies ops along with the updated index in a single batch
+func (l *storageLog) commit(ctx context.Context, index storageIndex, ops ...storage.Operation) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	if err := l.client.Batch(ctx, append(ops, storage.SetOperation(storageIndexKey, data))...); err != nil {
+		return fmt.Errorf("failed to persist wal: %w", err)
+	}
+	l.index = index
+	return nil
+}
+
+func (l *storageLog) load(ctx context.Context, ids []uint64) ([]Entry, error) {
+	entries := make([]Entry, 0, len(ids))
+	for _, id := range ids {
+		data, err := l.client.Get(ctx, storageRecordKey(id))
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+
+		record, err := decodeRecord(data)
+		if err != nil {
+			l.logger.Error("Skipping corrupt wal record", zap.Uint64("id", id), zap.Error(err))
+			continue
+		}
+		entries = append(entries, Entry{ID: id, Record: record})
+	}
+	return entries, nil
+}
@@ -0,0 +1,59 @@
+// Package wal implements a write-ahead log of the raw batches accepted by the receiver,
+// batches are persisted before being acknowledged and removed once consumed
+package wal
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Record is a raw batch as received from NiFi
+type Record struct {
+	// Kind identifies the type of events in the batch, e.g. provenance or bulletins
+	Kind        string    `json:"kind"`
+	ContentType string    `json:"contentType,omitempty"`
+	Body        []byte    `json:"body"`
+	ReceivedAt  time.Time `json:"receivedAt"`
+
+	// Reason is why the batch was dead-lettered, empty for pending batches
+	Reason string `json:"reason,omitempty"`
+}
+
+// Entry is a record along with its position in the log
+type Entry struct {
+	ID     uint64
+	Record Record
+}
+
+// Log persists batches until they are consumed
+type Log interface {
+	// Append persists the record and returns its id
+	Append(ctx context.Context, record Record) (uint64, error)
+
+	// Remove deletes a pending record once it has been consumed
+	Remove(ctx context.Context, id uint64) error
+
+	// DeadLetter moves a pending record to the dead-letter area
+	DeadLetter(ctx context.Context, id uint64, reason string) error
+
+	// Pending returns at most limit records not yet consumed whose id is at least from, in the
+	// order they were appended. Records that can't be decoded are quarantined and skipped
+	Pending(ctx context.Context, from uint64, limit int) ([]Entry, error)
+
+	// DeadLetters returns the dead-lettered records, in the order they were appended
+	DeadLetters(ctx context.Context) ([]Entry, error)
+
+	// Close releases the resources held by the log
+	Close(ctx context.Context) error
+}
+
+func encodeRecord(record Record) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func decodeRecord(data []byte) (Record, error) {
+	var record Record
+	err := json.Unmarshal(data, &record)
+	return record, err
+}
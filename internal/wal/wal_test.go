@@ -0,0 +1,186 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+)
+
+// mapClient is an in-memory storage client
+type mapClient map[string][]byte
+
+func (m mapClient) Get(_ context.Context, key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m mapClient) Set(_ context.Context, key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapClient) Delete(_ context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m mapClient) Batch(ctx context.Context, ops ...storage.Operation) error {
+	for _, op := range ops {
+		switch op.Type {
+		case storage.Get:
+			op.Value = m[op.Key]
+		case storage.Set:
+			m[op.Key] = op.Value
+		case storage.Delete:
+			delete(m, op.Key)
+		}
+	}
+	return nil
+}
+
+func (m mapClient) Close(context.Context) error {
+	return nil
+}
+
+func TestLog(t *testing.T) {
+	client := mapClient{}
+	dir := t.TempDir()
+
+	backends := map[string]func() (Log, error){
+		"storage": func() (Log, error) { return NewStorageLog(context.Background(), zap.NewNop(), client) },
+		"dir":     func() (Log, error) { return NewDirLog(zap.NewNop(), dir) },
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log, err := open()
+			require.NoError(t, err)
+
+			var ids []uint64
+			for _, body := range []string{"first", "second", "third"} {
+				id, err := log.Append(ctx, Record{Kind: "provenance", Body: []byte(body), ReceivedAt: time.Now()})
+				require.NoError(t, err)
+				ids = append(ids, id)
+			}
+
+			require.NoError(t, log.Remove(ctx, ids[0]))
+			require.NoError(t, log.DeadLetter(ctx, ids[1], "rejected"))
+			require.NoError(t, log.Close(ctx))
+
+			// reopening the log simulates a restart
+			log, err = open()
+			require.NoError(t, err)
+
+			pending, err := log.Pending(ctx, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, pending, 1)
+			assert.Equal(t, ids[2], pending[0].ID)
+			assert.Equal(t, "third", string(pending[0].Record.Body))
+
+			pending, err = log.Pending(ctx, ids[2]+1, 10)
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+
+			deadLetters, err := log.DeadLetters(ctx)
+			require.NoError(t, err)
+			require.Len(t, deadLetters, 1)
+			assert.Equal(t, "second", string(deadLetters[0].Record.Body))
+			assert.Equal(t, "rejected", deadLetters[0].Record.Reason)
+
+			// ids are never reused after a restart
+			id, err := log.Append(ctx, Record{Kind: "bulletins"})
+			require.NoError(t, err)
+			assert.Greater(t, id, ids[2])
+		})
+	}
+}
+
+func TestLogPendingPages(t *testing.T) {
+	backends := map[string]func() (Log, error){
+		"storage": func() (Log, error) { return NewStorageLog(context.Background(), zap.NewNop(), mapClient{}) },
+		"dir":     func() (Log, error) { return NewDirLog(zap.NewNop(), t.TempDir()) },
+	}
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log, err := open()
+			require.NoError(t, err)
+
+			var ids []uint64
+			for _, body := range []string{"first", "second", "third"} {
+				id, err := log.Append(ctx, Record{Kind: "provenance", Body: []byte(body)})
+				require.NoError(t, err)
+				ids = append(ids, id)
+			}
+
+			page, err := log.Pending(ctx, 0, 2)
+			require.NoError(t, err)
+			require.Len(t, page, 2)
+			assert.Equal(t, ids[:2], []uint64{page[0].ID, page[1].ID})
+
+			page, err = log.Pending(ctx, page[1].ID+1, 2)
+			require.NoError(t, err)
+			require.Len(t, page, 1)
+			assert.Equal(t, "third", string(page[0].Record.Body))
+		})
+	}
+}
+
+func TestLogPendingCorrupt(t *testing.T) {
+	client := mapClient{}
+	dir := t.TempDir()
+
+	backends := map[string]struct {
+		open    func() (Log, error)
+		corrupt func(id uint64)
+	}{
+		"storage": {
+			open:    func() (Log, error) { return NewStorageLog(context.Background(), zap.NewNop(), client) },
+			corrupt: func(id uint64) { client[storageRecordKey(id)] = []byte("{") },
+		},
+		"dir": {
+			open: func() (Log, error) { return NewDirLog(zap.NewNop(), dir) },
+			corrupt: func(id uint64) {
+				require.NoError(t, os.WriteFile((&dirLog{dir: dir}).segmentPath(pendingDir, id), []byte("{"), 0o640))
+			},
+		},
+	}
+
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			log, err := backend.open()
+			require.NoError(t, err)
+
+			var ids []uint64
+			for _, body := range []string{"first", "second", "third"} {
+				id, err := log.Append(ctx, Record{Kind: "provenance", Body: []byte(body)})
+				require.NoError(t, err)
+				ids = append(ids, id)
+			}
+			backend.corrupt(ids[1])
+
+			// the corrupt record is skipped and doesn't block the others
+			pending, err := log.Pending(ctx, 0, 10)
+			require.NoError(t, err)
+			require.Len(t, pending, 2)
+			assert.Equal(t, []uint64{ids[0], ids[2]}, []uint64{pending[0].ID, pending[1].ID})
+
+			// it is quarantined, so later reads don't come across it again
+			require.NoError(t, log.Close(ctx))
+			log, err = backend.open()
+			require.NoError(t, err)
+			pending, err = log.Pending(ctx, ids[1], 1)
+			require.NoError(t, err)
+			require.Len(t, pending, 1)
+			assert.Equal(t, ids[2], pending[0].ID)
+		})
+	}
+}
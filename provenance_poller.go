@@ -0,0 +1,318 @@
+package nifireceiver
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+)
+
+const (
+	provenanceCursorKey = "provenance_cursor"
+
+	// provenanceCursorLookback is subtracted from the cursor time when querying, events of
+	// a cluster are not always indexed in order, already consumed events are filtered by id
+	provenanceCursorLookback = time.Minute
+
+	// provenanceQueryPollInterval is the interval between checks of a running provenance query
+	provenanceQueryPollInterval = 250 * time.Millisecond
+)
+
+// provenanceEventNamespace is used to derive stable event uuids from node ids and event ids
+var provenanceEventNamespace = uuid.MustParse("5c4e2a38-6f0e-4a63-9b44-0b9b8e3c1f6d")
+
+// provenanceCursor tracks the last provenance event consumed from each NiFi node
+type provenanceCursor struct {
+	EventTime int64            `json:"eventTime"`
+	EventIDs  map[string]int64 `json:"eventIds"`
+}
+
+// seen returns true if the event was already consumed
+func (c *provenanceCursor) seen(event nifiapi.ProvenanceEventDTO) bool {
+	last, ok := c.EventIDs[event.ClusterNodeID]
+	return ok && event.EventID <= last
+}
+
+// provenancePoller polls the NiFi provenance API for new events
+type provenancePoller struct {
+	logger  *zap.Logger
+	config  APIConfig
+	client  *nifiapi.Client
+	storage storage.Client
+	consume func(context.Context, []translator.ProvenanceEvent) error
+
+	cursor     provenanceCursor
+	groupNames *groupNameCache
+
+	// location resolves the zone abbreviation of the event times
+	location *time.Location
+}
+
+func newProvenancePoller(
+	logger *zap.Logger,
+	config APIConfig,
+	client *nifiapi.Client,
+	groupNames *groupNameCache,
+	storageClient storage.Client,
+	consume func(context.Context, []translator.ProvenanceEvent) error,
+) *provenancePoller {
+	return &provenancePoller{
+		logger:     logger,
+		config:     config,
+		client:     client,
+		storage:    storageClient,
+		consume:    consume,
+		groupNames: groupNames,
+		location:   config.location(),
+	}
+}
+
+// loadCursor restores the cursor from storage, starting from now when no cursor was persisted
+func (p *provenancePoller) loadCursor(ctx context.Context) error {
+	p.cursor = provenanceCursor{EventTime: time.Now().UnixMilli(), EventIDs: make(map[string]int64)}
+
+	data, err := p.storage.Get(ctx, provenanceCursorKey)
+	if err != nil {
+		return fmt.Errorf("failed to load provenance cursor: %w", err)
+	}
+
+	if data == nil {
+		return nil
+	}
+
+	var cursor provenanceCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		p.logger.Warn("ignoring invalid provenance cursor", zap.Error(err))
+		return nil
+	}
+
+	if cursor.EventIDs == nil {
+		cursor.EventIDs = make(map[string]int64)
+	}
+
+	p.cursor = cursor
+	return nil
+}
+
+// run polls the provenance API every collection interval until ctx is done
+func (p *provenancePoller) run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.CollectionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.poll(ctx); err != nil {
+				p.logger.Error("Failed to poll provenance events", zap.Error(err))
+			}
+		}
+	}
+}
+
+// poll fetches and consumes all the events newer than the cursor
+func (p *provenancePoller) poll(ctx context.Context) error {
+	events, err := p.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(events, func(a, b nifiapi.ProvenanceEventDTO) int {
+		if c := cmp.Compare(parseEventTime(a.EventTime, p.location), parseEventTime(b.EventTime, p.location)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.EventID, b.EventID)
+	})
+
+	translated := make([]translator.ProvenanceEvent, 0, len(events))
+	for _, event := range events {
+		translated = append(translated, p.toProvenanceEvent(ctx, event))
+	}
+
+	if err := p.consume(ctx, translated); err != nil {
+		return fmt.Errorf("failed to consume provenance events: %w", err)
+	}
+
+	for _, event := range events {
+		p.cursor.EventIDs[event.ClusterNodeID] = max(p.cursor.EventIDs[event.ClusterNodeID], event.EventID)
+		p.cursor.EventTime = max(p.cursor.EventTime, parseEventTime(event.EventTime, p.location))
+	}
+
+	data, err := json.Marshal(p.cursor)
+	if err != nil {
+		return fmt.Errorf("failed to encode provenance cursor: %w", err)
+	}
+
+	if err := p.storage.Set(ctx, provenanceCursorKey, data); err != nil {
+		return fmt.Errorf("failed to persist provenance cursor: %w", err)
+	}
+	return nil
+}
+
+// fetch pages backwards from now until reaching the cursor, NiFi returns the most
+// recent events of a query so older pages are requested by moving the end date
+func (p *provenancePoller) fetch(ctx context.Context) ([]nifiapi.ProvenanceEventDTO, error) {
+	startDate := time.UnixMilli(p.cursor.EventTime).Add(-provenanceCursorLookback).UTC().Format(nifiapi.DateFormat)
+	endDate := ""
+
+	var results []nifiapi.ProvenanceEventDTO
+	collected := make(map[string]bool)
+	for {
+		page, err := p.query(ctx, nifiapi.ProvenanceRequestDTO{
+			MaxResults: p.config.Provenance.MaxResults,
+			StartDate:  startDate,
+			EndDate:    endDate,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var oldest int64
+		newEvents := 0
+		for _, event := range page {
+			ts := parseEventTime(event.EventTime, p.location)
+			if oldest == 0 || ts < oldest {
+				oldest = ts
+			}
+
+			key := fmt.Sprintf("%s/%d", event.ClusterNodeID, event.EventID)
+			if p.cursor.seen(event) || collected[key] {
+				continue
+			}
+
+			collected[key] = true
+			results = append(results, event)
+			newEvents++
+		}
+
+		if len(page) < p.config.Provenance.MaxResults || newEvents == 0 {
+			return results, nil
+		}
+
+		nextEndDate := time.UnixMilli(oldest).UTC().Format(nifiapi.DateFormat)
+		if nextEndDate == endDate {
+			p.logger.Warn("provenance page holds a single second of events, consider raising max_results",
+				zap.Int("max_results", p.config.Provenance.MaxResults))
+			return results, nil
+		}
+		endDate = nextEndDate
+	}
+}
+
+// query runs a single provenance query and waits for its results
+func (p *provenancePoller) query(ctx context.Context, request nifiapi.ProvenanceRequestDTO) ([]nifiapi.ProvenanceEventDTO, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Provenance.QueryTimeout)
+	defer cancel()
+
+	provenance, err := p.client.SubmitProvenanceQuery(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit provenance query: %w", err)
+	}
+
+	defer func() {
+		// the query context may already be expired, use a fresh one to release the query
+		deleteCtx, deleteCancel := context.WithTimeout(context.Background(), p.config.Provenance.QueryTimeout)
+		defer deleteCancel()
+		if err := p.client.DeleteProvenanceQuery(deleteCtx, provenance.ID); err != nil {
+			p.logger.Warn("failed to delete provenance query", zap.String("id", provenance.ID), zap.Error(err))
+		}
+	}()
+
+	for !provenance.Finished {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("provenance query did not finish: %w", ctx.Err())
+		case <-time.After(provenanceQueryPollInterval):
+		}
+
+		provenance, err = p.client.GetProvenanceQuery(ctx, provenance.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provenance query: %w", err)
+		}
+	}
+
+	if provenance.Results == nil {
+		return nil, nil
+	}
+
+	for _, msg := range provenance.Results.Errors {
+		p.logger.Warn("provenance query reported an error", zap.String("error", msg))
+	}
+	return provenance.Results.ProvenanceEvents, nil
+}
+
+// toProvenanceEvent maps a REST API event into the reporting task's event format
+func (p *provenancePoller) toProvenanceEvent(ctx context.Context, event nifiapi.ProvenanceEventDTO) translator.ProvenanceEvent {
+	timestamp := parseEventTime(event.EventTime, p.location)
+	result := translator.ProvenanceEvent{
+		EventId:             uuid.NewSHA1(provenanceEventNamespace, []byte(fmt.Sprintf("%s/%d", event.ClusterNodeID, event.EventID))).String(),
+		EventOrdinal:        event.EventID,
+		EventType:           translator.ProvenanceEventType(event.EventType),
+		TimestampMillis:     timestamp,
+		Details:             event.Details,
+		ComponentId:         event.ComponentID,
+		ComponentType:       event.ComponentType,
+		ComponentName:       event.ComponentName,
+		ProcessGroupId:      event.GroupID,
+		ProcessGroupName:    p.groupNames.get(ctx, event.GroupID),
+		EntityId:            event.FlowFileUUID,
+		EntityType:          "org.apache.nifi.flowfile.FlowFile",
+		EntitySize:          event.FileSizeBytes,
+		UpdatedAttributes:   make(map[string]string),
+		PreviousAttributes:  make(map[string]string),
+		ActorHostname:       event.ClusterNodeAddress,
+		ParentIds:           event.ParentUUIDs,
+		ChildIds:            event.ChildUUIDs,
+		Platform:            "nifi",
+		RemoteIdentifier:    event.SourceSystemFlowFileID,
+		AlternateIdentifier: event.AlternateIdentifierURI,
+		TransitUri:          event.TransitURI,
+	}
+
+	if event.EventDuration != nil && *event.EventDuration > 0 {
+		result.DurationMillis = *event.EventDuration
+	}
+
+	if event.LineageDuration != nil {
+		result.LineageStart = timestamp - *event.LineageDuration
+	}
+
+	if event.InputContentClaimFileSizeBytes != nil {
+		result.PreviousEntitySize = *event.InputContentClaimFileSizeBytes
+	}
+
+	for _, attr := range event.Attributes {
+		if attr.Value != nil {
+			result.UpdatedAttributes[attr.Name] = *attr.Value
+		}
+		if attr.PreviousValue != nil {
+			result.PreviousAttributes[attr.Name] = *attr.PreviousValue
+		}
+	}
+
+	return result
+}
+
+// parseEventTime parses the event time returned by the REST API into unix milliseconds, the
+// zone abbreviation is resolved in loc since time.Parse takes unknown abbreviations for UTC
+func parseEventTime(value string, loc *time.Location) int64 {
+	ts, err := time.ParseInLocation(nifiapi.EventTimeFormat, value, loc)
+	if err != nil {
+		return 0
+	}
+	return ts.UnixMilli()
+}
@@ -0,0 +1,144 @@
+package nifireceiver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+)
+
+// fakeNifi is a minimal stand-in for the NiFi provenance REST API
+type fakeNifi struct {
+	mu      sync.Mutex
+	events  []nifiapi.ProvenanceEventDTO
+	queries map[string]nifiapi.ProvenanceDTO
+}
+
+func (f *fakeNifi) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == "/nifi-api/provenance":
+		var entity nifiapi.ProvenanceEntity
+		_ = json.NewDecoder(req.Body).Decode(&entity)
+		id := time.Now().Format(time.RFC3339Nano)
+		f.queries[id] = nifiapi.ProvenanceDTO{ID: id, Request: entity.Provenance.Request}
+		_ = json.NewEncoder(w).Encode(nifiapi.ProvenanceEntity{Provenance: f.queries[id]})
+	case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/nifi-api/provenance/"):
+		query := f.queries[strings.TrimPrefix(req.URL.Path, "/nifi-api/provenance/")]
+		query.Finished = true
+		query.Results = &nifiapi.ProvenanceResultsDTO{ProvenanceEvents: f.search(query.Request)}
+		_ = json.NewEncoder(w).Encode(nifiapi.ProvenanceEntity{Provenance: query})
+	case req.Method == http.MethodDelete && strings.HasPrefix(req.URL.Path, "/nifi-api/provenance/"):
+		delete(f.queries, strings.TrimPrefix(req.URL.Path, "/nifi-api/provenance/"))
+	case req.Method == http.MethodGet && strings.HasPrefix(req.URL.Path, "/nifi-api/process-groups/"):
+		_ = json.NewEncoder(w).Encode(nifiapi.ProcessGroupEntity{Component: nifiapi.ProcessGroupDTO{Name: "ingest"}})
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// search returns the most recent events matching the request, like NiFi does
+func (f *fakeNifi) search(request nifiapi.ProvenanceRequestDTO) []nifiapi.ProvenanceEventDTO {
+	start, _ := time.Parse(nifiapi.DateFormat, request.StartDate)
+	end, err := time.Parse(nifiapi.DateFormat, request.EndDate)
+	if err != nil {
+		end = time.Now().Add(time.Hour)
+	}
+
+	var results []nifiapi.ProvenanceEventDTO
+	for _, event := range f.events {
+		ts := time.UnixMilli(parseEventTime(event.EventTime, time.UTC)).Truncate(time.Second)
+		if !ts.Before(start) && !ts.After(end) {
+			results = append(results, event)
+		}
+	}
+
+	slices.Reverse(results)
+	return results[:min(len(results), request.MaxResults)]
+}
+
+func newFakeEvent(id int64, ts time.Time) nifiapi.ProvenanceEventDTO {
+	value := "value"
+	return nifiapi.ProvenanceEventDTO{
+		EventID:       id,
+		EventTime:     ts.UTC().Format(nifiapi.EventTimeFormat),
+		EventType:     "CREATE",
+		FlowFileUUID:  "1f3c9a54-0c52-4d3e-9d4a-6f6d7b1c2e11",
+		GroupID:       "group",
+		ComponentName: "GenerateFlowFile",
+		Attributes:    []nifiapi.AttributeDTO{{Name: "key", Value: &value}},
+	}
+}
+
+func TestProvenancePollerPaging(t *testing.T) {
+	now := time.Now()
+	nifi := &fakeNifi{queries: make(map[string]nifiapi.ProvenanceDTO)}
+	for i := int64(1); i <= 5; i++ {
+		nifi.events = append(nifi.events, newFakeEvent(i, now.Add(time.Duration(i-5)*2*time.Second)))
+	}
+
+	server := httptest.NewServer(nifi)
+	defer server.Close()
+
+	var consumed []translator.ProvenanceEvent
+	consume := func(_ context.Context, events []translator.ProvenanceEvent) error {
+		consumed = append(consumed, events...)
+		return nil
+	}
+
+	cfg := createDefaultConfig().(*Config).API
+	cfg.Provenance.MaxResults = 2
+
+	client := nifiapi.NewClient(server.Client(), server.URL)
+	poller := newProvenancePoller(zap.NewNop(), cfg, client, newGroupNameCache(zap.NewNop(), client), storage.NewNopClient(), consume)
+	require.NoError(t, poller.loadCursor(context.Background()))
+	poller.cursor.EventTime = now.Add(-10 * time.Second).UnixMilli()
+
+	require.NoError(t, poller.poll(context.Background()))
+	require.Len(t, consumed, 5)
+	for i, event := range consumed {
+		assert.Equal(t, int64(i+1), event.EventOrdinal)
+		assert.Equal(t, "ingest", event.ProcessGroupName)
+		assert.Equal(t, "value", event.UpdatedAttributes["key"])
+	}
+
+	// Polling again should not return the same events
+	consumed = nil
+	require.NoError(t, poller.poll(context.Background()))
+	assert.Empty(t, consumed)
+
+	nifi.mu.Lock()
+	nifi.events = append(nifi.events, newFakeEvent(6, now))
+	nifi.mu.Unlock()
+
+	require.NoError(t, poller.poll(context.Background()))
+	require.Len(t, consumed, 1)
+	assert.Equal(t, int64(6), consumed[0].EventOrdinal)
+	assert.Empty(t, nifi.queries, "provenance queries should be deleted")
+}
+
+func TestParseEventTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// abbreviations are resolved in the configured time zone, summer and winter time alike
+	assert.Equal(t, time.Date(2024, 7, 1, 12, 5, 0, 123e6, time.UTC).UnixMilli(), parseEventTime("07/01/2024 14:05:00.123 CEST", berlin))
+	assert.Equal(t, time.Date(2024, 1, 15, 13, 5, 0, 0, time.UTC).UnixMilli(), parseEventTime("01/15/2024 14:05:00.000 CET", berlin))
+	assert.Equal(t, time.Date(2024, 7, 1, 14, 5, 0, 0, time.UTC).UnixMilli(), parseEventTime("07/01/2024 14:05:00.000 UTC", berlin))
+	assert.Zero(t, parseEventTime("invalid", berlin))
+}
@@ -0,0 +1,85 @@
+package nifireceiver
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// errQueueFull is returned when the ingestion queue can't accept more work
+var errQueueFull = errors.New("ingestion queue is full")
+
+// ingestQueue is a bounded in-memory queue between the HTTP handlers and the
+// pipelines, drained by a fixed number of workers
+type ingestQueue struct {
+	logger *zap.Logger
+	items  chan func(context.Context) error
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newIngestQueue(logger *zap.Logger, size int) *ingestQueue {
+	return &ingestQueue{
+		logger: logger,
+		items:  make(chan func(context.Context) error, size),
+	}
+}
+
+// start starts the workers draining the queue
+func (q *ingestQueue) start(workers int) {
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go func() {
+			defer q.wg.Done()
+			for fn := range q.items {
+				if err := fn(context.Background()); err != nil {
+					q.logger.Error("Failed to consume queued events", zap.Error(err))
+				}
+			}
+		}()
+	}
+}
+
+// offer queues fn without blocking, returning errQueueFull when there is no room left
+func (q *ingestQueue) offer(fn func(context.Context) error) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return errQueueFull
+	}
+
+	select {
+	case q.items <- fn:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// shutdown stops accepting work and waits for the queued work to be drained
+func (q *ingestQueue) shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
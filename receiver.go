@@ -1,60 +1,225 @@
 package nifireceiver
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/metadata"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/nifiapi"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/sitetosite"
 	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/wal"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
 	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/receiverhelper"
 	"go.uber.org/zap"
 )
 
+// kinds of batches persisted in the write-ahead log
+const (
+	walKindProvenance = "provenance"
+	walKindBulletins  = "bulletins"
+)
+
+// walPageSize is the number of pending batches read from the write-ahead log at once
+const walPageSize = 100
+
+// errInvalidPayload is returned when a persisted batch can't be decoded
+var errInvalidPayload = errors.New("invalid payload")
+
+// memoryLimiter is implemented by the memory_limiter extension
+type memoryLimiter interface {
+	MustRefuse() bool
+}
+
 type nifiReceiver struct {
-	address         string
-	config          *Config
-	params          receiver.CreateSettings
-	nextConsumer    consumer.Traces
-	server          *http.Server
-	tReceiver       *receiverhelper.ObsReport
-	eventTranslator translator.EventTranslator
+	address             string
+	config              *Config
+	params              receiver.CreateSettings
+	nextTracesConsumer  consumer.Traces
+	nextMetricsConsumer consumer.Metrics
+	nextLogsConsumer    consumer.Logs
+	server              *http.Server
+	obsrecv             *receiverhelper.ObsReport
+	eventTranslator     translator.EventTranslator
+	translatorSettings  translator.Settings
+	telemetry           *receiverTelemetry
+
+	queue         *ingestQueue
+	memoryLimiter memoryLimiter
+	wal           wal.Log
+
+	// walInFlight holds the ids of the batches being consumed so a retry doesn't consume them twice,
+	// walAppendMu keeps a retry from reading the log while a batch is appended but not yet in flight
+	walAppendMu sync.RWMutex
+	walMu       sync.Mutex
+	walInFlight map[uint64]struct{}
+
+	storageClients   []storage.Client
+	backgroundCancel context.CancelFunc
+	backgroundWG     sync.WaitGroup
+
+	startOnce    sync.Once
+	startErr     error
+	shutdownOnce sync.Once
+	shutdownErr  error
 }
 
-func newNifiReceiver(config *Config, nextConsumer consumer.Traces, params receiver.CreateSettings) (receiver.Traces, error) {
-	if nextConsumer == nil {
-		return nil, component.ErrNilNextConsumer
+func newNifiReceiver(config *Config, params receiver.CreateSettings) (*nifiReceiver, error) {
+	instance, err := receiverhelper.NewObsReport(receiverhelper.ObsReportSettings{LongLivedCtx: false, ReceiverID: params.ID, Transport: "http", ReceiverCreateSettings: params})
+	if err != nil {
+		return nil, err
 	}
 
-	instance, err := receiverhelper.NewObsReport(receiverhelper.ObsReportSettings{LongLivedCtx: false, ReceiverID: params.ID, Transport: "http", ReceiverCreateSettings: params})
+	telemetry, err := newReceiverTelemetry(params.TelemetrySettings)
 	if err != nil {
 		return nil, err
 	}
 
-	et := translator.NewEventTranslator(params.Logger, config.IgnoredEventTypes, config.ContextPropagationAliases)
+	settings := translator.Settings{
+		IgnoredEventTypes:         config.IgnoredEventTypes,
+		ContextPropagationAliases: config.ContextPropagationAliases,
+		SpanContextStore:          translator.NewLRUSpanContextStore(config.SpanContextStore.TTL, config.SpanContextStore.MaxEntries),
+		ChainEvents:               config.Traces.ChainEvents,
+		RootSpans:                 config.Traces.RootSpan,
+		ForkStrategy:              config.Lineage.Fork,
+		JoinStrategy:              config.Lineage.Join,
+		ResourceKeys:              config.Resource.Keys,
+		RecordAttributeChanges:    config.Attributes.RecordChanges,
+		OmitSpanAttributes:        !config.Attributes.onSpans(),
+	}
+	if config.Resource.ServiceName != "" {
+		if settings.ServiceName, err = translator.ParseServiceNameTemplate(config.Resource.ServiceName); err != nil {
+			return nil, err
+		}
+	}
+	if settings.Attributes, err = translator.NewAttributeFilter(config.Attributes.filterSettings()); err != nil {
+		return nil, err
+	}
+	if config.Deduplication.Enabled {
+		settings.DeduplicationTTL = config.Deduplication.TTL
+		settings.DeduplicationMaxEntries = config.Deduplication.MaxEntries
+	}
+	if config.BulletinHold.Enabled {
+		settings.BulletinHoldTimeout = config.BulletinHold.Timeout
+		settings.BulletinHoldMaxEntries = config.BulletinHold.MaxEntries
+	}
+
 	return &nifiReceiver{
-		params:          params,
-		config:          config,
-		nextConsumer:    nextConsumer,
-		server:          &http.Server{},
-		tReceiver:       instance,
-		eventTranslator: et,
+		params:             params,
+		config:             config,
+		server:             &http.Server{},
+		obsrecv:            instance,
+		eventTranslator:    translator.NewEventTranslator(params.Logger, settings),
+		translatorSettings: settings,
+		telemetry:          telemetry,
 	}, nil
 }
 
-// Start the receiver and listen for traces
-func (r *nifiReceiver) Start(_ context.Context, host component.Host) error {
+func (r *nifiReceiver) registerTracesConsumer(nextConsumer consumer.Traces) error {
+	if nextConsumer == nil {
+		return component.ErrNilNextConsumer
+	}
+	r.nextTracesConsumer = nextConsumer
+	return nil
+}
+
+func (r *nifiReceiver) registerMetricsConsumer(nextConsumer consumer.Metrics) error {
+	if nextConsumer == nil {
+		return component.ErrNilNextConsumer
+	}
+	r.nextMetricsConsumer = nextConsumer
+	return nil
+}
+
+func (r *nifiReceiver) registerLogsConsumer(nextConsumer consumer.Logs) error {
+	if nextConsumer == nil {
+		return component.ErrNilNextConsumer
+	}
+	r.nextLogsConsumer = nextConsumer
+	return nil
+}
+
+// Start the receiver and listen for events, the receiver is shared between
+// pipelines so only the first call starts the server
+func (r *nifiReceiver) Start(ctx context.Context, host component.Host) error {
+	r.startOnce.Do(func() {
+		r.startErr = r.start(ctx, host)
+	})
+	return r.startErr
+}
+
+func (r *nifiReceiver) start(_ context.Context, host component.Host) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc(r.config.BulletinURLPath, r.handleBulletinEvents)
 	mux.HandleFunc(r.config.ProvenanceURLPath, r.handleProvenanceEvents)
+	if r.config.SiteToSite.Enabled {
+		mux.Handle("/nifi-api/", sitetosite.NewServer(r.params.Logger, sitetosite.Settings{
+			Name:              r.params.ID.String(),
+			PortNames:         []string{r.config.SiteToSite.ProvenancePortName, r.config.SiteToSite.BulletinPortName},
+			AdvertisedAddress: r.config.SiteToSite.AdvertisedAddress,
+			TransactionTTL:    r.config.SiteToSite.TransactionTTL,
+		}, r.receiveSiteToSite))
+	}
+
+	if r.config.MemoryLimiterID != nil {
+		ext, ok := host.GetExtensions()[*r.config.MemoryLimiterID]
+		if !ok {
+			return fmt.Errorf("memory limiter extension %q not found", r.config.MemoryLimiterID)
+		}
+
+		r.memoryLimiter, ok = ext.(memoryLimiter)
+		if !ok {
+			return fmt.Errorf("extension %q is not a memory limiter", r.config.MemoryLimiterID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.backgroundCancel = cancel
+
+	if r.config.SpanContextStore.StorageID != nil {
+		storageClient, err := getStorageClient(ctx, host, r.config.SpanContextStore.StorageID, r.params.ID, "span_contexts")
+		if err != nil {
+			return err
+		}
+
+		store, err := translator.NewStorageSpanContextStore(ctx, r.params.Logger, storageClient,
+			r.config.SpanContextStore.TTL, r.config.SpanContextStore.MaxEntries)
+		if err != nil {
+			return err
+		}
+
+		r.translatorSettings.SpanContextStore = store
+		r.eventTranslator = translator.NewEventTranslator(r.params.Logger, r.translatorSettings)
+	}
+
+	if err := r.startJanitor(ctx); err != nil {
+		return err
+	}
+
+	if r.config.Queue.Enabled {
+		r.queue = newIngestQueue(r.params.Logger, r.config.Queue.Size)
+		r.queue.start(r.config.Queue.NumWorkers)
+	}
+
+	if r.config.WAL.Enabled {
+		if err := r.startWAL(ctx, host); err != nil {
+			return err
+		}
+	}
 
 	var err error
-	r.server, err = r.config.ServerConfig.ToServer(host, r.params.TelemetrySettings, mux)
+	r.server, err = r.config.ServerConfig.ToServer(host, r.params.TelemetrySettings, r.withBackpressure(mux))
 	if err != nil {
 		return fmt.Errorf("failed to create server definition: %w", err)
 	}
@@ -75,70 +240,662 @@ func (r *nifiReceiver) Start(_ context.Context, host component.Host) error {
 			r.params.TelemetrySettings.ReportStatus(component.NewFatalErrorEvent(fmt.Errorf("error starting nifi receiver: %w", err)))
 		}
 	}()
+
+	return r.startPollers(ctx, host)
+}
+
+// startJanitor periodically removes the expired span contexts and releases the bulletins held
+// past their timeout, and reports the store's telemetry
+func (r *nifiReceiver) startJanitor(ctx context.Context) error {
+	store := r.translatorSettings.SpanContextStore
+	registration, err := r.telemetry.observeStore(store)
+	if err != nil {
+		return fmt.Errorf("failed to register span context store telemetry: %w", err)
+	}
+
+	r.backgroundWG.Add(1)
+	go func() {
+		defer r.backgroundWG.Done()
+		defer func() { _ = registration.Unregister() }()
+
+		interval := r.config.SpanContextStore.ExpiryInterval
+		if timeout := r.translatorSettings.BulletinHoldTimeout; timeout > 0 {
+			interval = min(interval, timeout)
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				store.Expire(now)
+				r.releaseHeldBulletins(ctx, now)
+			}
+		}
+	}()
+	return nil
+}
+
+// startWAL opens the write-ahead log, replays the batches left pending by a previous run
+// and periodically retries the batches left pending after a transient failure
+func (r *nifiReceiver) startWAL(ctx context.Context, host component.Host) error {
+	var err error
+	if r.config.WAL.StorageID != nil {
+		var storageClient storage.Client
+		storageClient, err = getStorageClient(ctx, host, r.config.WAL.StorageID, r.params.ID, "wal")
+		if err != nil {
+			return err
+		}
+		r.wal, err = wal.NewStorageLog(ctx, r.params.Logger, storageClient)
+	} else {
+		r.wal, err = wal.NewDirLog(r.params.Logger, r.config.WAL.Directory)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open write-ahead log: %w", err)
+	}
+
+	r.walInFlight = make(map[uint64]struct{})
+	r.backgroundWG.Add(1)
+	go func() {
+		defer r.backgroundWG.Done()
+
+		replayed, err := r.replayPendingRecords(ctx)
+		if err != nil {
+			r.params.Logger.Error("Failed to replay write-ahead log", zap.Error(err))
+		} else if replayed > 0 {
+			r.params.Logger.Info("Replayed write-ahead log", zap.Int("batches", replayed))
+		}
+
+		ticker := time.NewTicker(r.config.WAL.RetryInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.replayPendingRecords(ctx); err != nil {
+					r.params.Logger.Error("Failed to retry pending batches", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// replayPendingRecords consumes the pending batches a page at a time, so the write-ahead log is
+// never read in memory as a whole. It returns the number of batches replayed
+func (r *nifiReceiver) replayPendingRecords(ctx context.Context) (int, error) {
+	var from uint64
+	replayed := 0
+	for ctx.Err() == nil {
+		pending, next, err := r.claimPendingRecords(ctx, from)
+		if err != nil {
+			return replayed, err
+		}
+		if next == from {
+			break
+		}
+
+		r.replayRecords(ctx, pending)
+		replayed += len(pending)
+		from = next
+	}
+	return replayed, nil
+}
+
+// claimPendingRecords reads a page of pending batches from the id from, it returns the ones not
+// already being consumed, marked in flight, and the id the next page starts from, from when
+// there are no more pending batches
+func (r *nifiReceiver) claimPendingRecords(ctx context.Context, from uint64) ([]wal.Entry, uint64, error) {
+	r.walAppendMu.Lock()
+	defer r.walAppendMu.Unlock()
+
+	pending, err := r.wal.Pending(ctx, from, walPageSize)
+	if err != nil {
+		return nil, from, fmt.Errorf("failed to read write-ahead log: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil, from, nil
+	}
+	next := pending[len(pending)-1].ID + 1
+
+	r.walMu.Lock()
+	defer r.walMu.Unlock()
+
+	claimed := pending[:0]
+	for _, entry := range pending {
+		if _, ok := r.walInFlight[entry.ID]; ok {
+			continue
+		}
+		r.walInFlight[entry.ID] = struct{}{}
+		claimed = append(claimed, entry)
+	}
+	return claimed, next, nil
+}
+
+// replayRecords consumes the claimed batches, those failing with a transient error are kept for the next retry
+func (r *nifiReceiver) replayRecords(ctx context.Context, pending []wal.Entry) {
+	for _, entry := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := r.settleRecord(ctx, entry.ID, r.consumeRecord(ctx, entry.Record), true)
+		r.releaseRecord(entry.ID)
+		if err != nil {
+			r.params.Logger.Error("Failed to replay batch, it will be retried", zap.Uint64("id", entry.ID), zap.Error(err))
+		}
+	}
+}
+
+func (r *nifiReceiver) releaseRecord(id uint64) {
+	r.walMu.Lock()
+	delete(r.walInFlight, id)
+	r.walMu.Unlock()
+}
+
+// startPollers starts polling the NiFi REST API when pull mode is enabled
+func (r *nifiReceiver) startPollers(ctx context.Context, host component.Host) error {
+	if !r.config.API.Provenance.Enabled && !r.config.API.Bulletins.Enabled {
+		return nil
+	}
+
+	httpClient, err := r.config.API.ToClient(host, r.params.TelemetrySettings)
+	if err != nil {
+		return fmt.Errorf("failed to create nifi api client: %w", err)
+	}
+
+	client := nifiapi.NewClient(httpClient, r.config.API.Endpoint)
+	groupNames := newGroupNameCache(r.params.Logger, client)
+
+	if r.config.API.Provenance.Enabled {
+		storageClient, err := getStorageClient(ctx, host, r.config.API.StorageID, r.params.ID, "provenance")
+		if err != nil {
+			return err
+		}
+		r.storageClients = append(r.storageClients, storageClient)
+
+		poller := newProvenancePoller(r.params.Logger, r.config.API, client, groupNames, storageClient, r.consumeProvenanceEvents)
+		if err := poller.loadCursor(ctx); err != nil {
+			return err
+		}
+
+		r.params.Logger.Info("Polling nifi provenance api", zap.String("endpoint", r.config.API.Endpoint))
+		r.backgroundWG.Add(1)
+		go func() {
+			defer r.backgroundWG.Done()
+			poller.run(ctx)
+		}()
+	}
+
+	if r.config.API.Bulletins.Enabled {
+		storageClient, err := getStorageClient(ctx, host, r.config.API.StorageID, r.params.ID, "bulletins")
+		if err != nil {
+			return err
+		}
+		r.storageClients = append(r.storageClients, storageClient)
+
+		poller := newBulletinPoller(r.params.Logger, r.config.API, client, groupNames, storageClient, r.consumeBulletinEvents)
+		if err := poller.loadCursor(ctx); err != nil {
+			return err
+		}
+
+		r.params.Logger.Info("Polling nifi bulletin board api", zap.String("endpoint", r.config.API.Endpoint))
+		r.backgroundWG.Add(1)
+		go func() {
+			defer r.backgroundWG.Done()
+			poller.run(ctx)
+		}()
+	}
+
 	return nil
 }
 
 // Shutdown the receiver
 func (r *nifiReceiver) Shutdown(ctx context.Context) (err error) {
-	return r.server.Shutdown(ctx)
+	r.shutdownOnce.Do(func() {
+		removeReceiver(r.config)
+		if r.backgroundCancel != nil {
+			r.backgroundCancel()
+			r.backgroundWG.Wait()
+		}
+
+		r.shutdownErr = r.server.Shutdown(ctx)
+		if r.queue != nil {
+			r.shutdownErr = errors.Join(r.shutdownErr, r.queue.shutdown(ctx))
+		}
+
+		// the held bulletins would be lost otherwise, released once no more bulletins can be
+		// received or drained from the queue
+		r.releaseHeldBulletins(ctx, time.Now().Add(r.translatorSettings.BulletinHoldTimeout))
+
+		if r.wal != nil {
+			r.shutdownErr = errors.Join(r.shutdownErr, r.wal.Close(ctx))
+		}
+
+		r.shutdownErr = errors.Join(r.shutdownErr, r.translatorSettings.SpanContextStore.Close(ctx))
+
+		for _, client := range r.storageClients {
+			r.shutdownErr = errors.Join(r.shutdownErr, client.Close(ctx))
+		}
+	})
+	return r.shutdownErr
+}
+
+// withBackpressure refuses requests while the memory limiter reports memory pressure
+func (r *nifiReceiver) withBackpressure(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.memoryLimiter != nil && r.memoryLimiter.MustRefuse() {
+			r.params.Logger.Debug("Refusing request due to memory pressure")
+			w.Header().Set("Retry-After", strconv.Itoa(int(r.config.Queue.RetryAfter.Seconds())))
+			http.Error(w, "Receiver is under memory pressure", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// dispatch consumes the events synchronously, or queues them when asynchronous ingestion is enabled
+func (r *nifiReceiver) dispatch(ctx context.Context, consume func(context.Context) error) error {
+	if r.queue == nil {
+		return consume(ctx)
+	}
+	return r.queue.offer(consume)
+}
+
+// writeResponse acknowledges a request, or reports why its events couldn't be consumed
+func (r *nifiReceiver) writeResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidPayload):
+		http.Error(w, "Failed to decode events", http.StatusBadRequest)
+		r.params.Logger.Error("Failed to decode events", zap.Error(err))
+	case errors.Is(err, errQueueFull):
+		w.Header().Set("Retry-After", strconv.Itoa(int(r.config.Queue.RetryAfter.Seconds())))
+		http.Error(w, "Ingestion queue is full", r.config.Queue.FullStatusCode)
+	case err != nil:
+		http.Error(w, "Failed to consume events", http.StatusInternalServerError)
+		r.params.Logger.Error("Failed to consume events", zap.Error(err))
+	case r.queue != nil:
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("Accepted"))
+	default:
+		_, _ = w.Write([]byte("OK"))
+	}
+}
+
+// handleDurable persists the request body to the write-ahead log before consuming it
+func (r *nifiReceiver) handleDurable(w http.ResponseWriter, req *http.Request, kind string) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "Failed to read events", http.StatusBadRequest)
+		r.params.Logger.Error("Failed to read events", zap.Error(err))
+		return
+	}
+
+	r.writeResponse(w, r.acceptRecord(req.Context(), wal.Record{
+		Kind:        kind,
+		ContentType: req.Header.Get("Content-Type"),
+		Body:        body,
+		ReceivedAt:  time.Now(),
+	}))
+}
+
+// acceptRecord appends the batch to the write-ahead log, then consumes it synchronously or queues it.
+// Once queued the batch is owned by the receiver and stays in the log until it is consumed
+func (r *nifiReceiver) acceptRecord(ctx context.Context, record wal.Record) error {
+	// a retry can't read the log until the appended batch is marked in flight
+	r.walAppendMu.RLock()
+	id, err := r.wal.Append(ctx, record)
+	if err == nil {
+		r.walMu.Lock()
+		r.walInFlight[id] = struct{}{}
+		r.walMu.Unlock()
+	}
+	r.walAppendMu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to persist events: %w", err)
+	}
+
+	if r.queue == nil {
+		defer r.releaseRecord(id)
+		return r.settleRecord(ctx, id, r.consumeRecord(ctx, record), false)
+	}
+
+	err = r.queue.offer(func(ctx context.Context) error {
+		defer r.releaseRecord(id)
+		return r.settleRecord(ctx, id, r.consumeRecord(ctx, record), true)
+	})
+	if err != nil {
+		err = errors.Join(err, r.wal.Remove(ctx, id))
+		r.releaseRecord(id)
+	}
+	return err
+}
+
+// settleRecord updates the write-ahead log according to the outcome of consuming a batch.
+// Consumed batches are removed and permanently rejected ones are dead-lettered, a batch failing
+// otherwise is kept for replay when owned by the receiver, or removed so NiFi sends it again
+func (r *nifiReceiver) settleRecord(ctx context.Context, id uint64, err error, owned bool) error {
+	switch {
+	case err == nil:
+		return r.wal.Remove(ctx, id)
+	case consumererror.IsPermanent(err), owned && errors.Is(err, errInvalidPayload):
+		r.params.Logger.Warn("Batch rejected, moving it to the dead-letter area", zap.Uint64("id", id), zap.Error(err))
+		return r.wal.DeadLetter(ctx, id, err.Error())
+	case owned:
+		return err
+	default:
+		return errors.Join(err, r.wal.Remove(ctx, id))
+	}
+}
+
+// consumeRecord decodes a persisted batch and sends it to the pipelines
+func (r *nifiReceiver) consumeRecord(ctx context.Context, record wal.Record) error {
+	switch record.Kind {
+	case walKindProvenance:
+		events, err := decodeProvenanceEvents(record.ContentType, bytes.NewReader(record.Body))
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidPayload, err)
+		}
+		return r.consumeProvenanceEvents(ctx, events)
+	case walKindBulletins:
+		events, err := decodeBulletinEvents(record.ContentType, bytes.NewReader(record.Body))
+		if err != nil {
+			return fmt.Errorf("%w: %w", errInvalidPayload, err)
+		}
+		return r.consumeBulletinEvents(ctx, events)
+	default:
+		return fmt.Errorf("%w: unknown batch kind %q", errInvalidPayload, record.Kind)
+	}
 }
 
 func (r *nifiReceiver) handleProvenanceEvents(w http.ResponseWriter, req *http.Request) {
-	obsCtx := r.tReceiver.StartTracesOp(req.Context())
-	var err error
-	var spanCount int
-	var provenanceEvents []translator.ProvenanceEvent
-	defer func(spanCount *int) {
-		r.tReceiver.EndTracesOp(obsCtx, metadata.Type.String(), *spanCount, err)
-	}(&spanCount)
+	if r.wal != nil {
+		r.handleDurable(w, req, walKindProvenance)
+		return
+	}
 
-	jsonDecoder := json.NewDecoder(req.Body)
-	err = jsonDecoder.Decode(&provenanceEvents)
+	if r.config.StreamingFlushSize > 0 {
+		r.handleProvenanceEventsStream(w, req)
+		return
+	}
+
+	provenanceEvents, err := decodeProvenanceEvents(req.Header.Get("Content-Type"), req.Body)
 	if err != nil {
-		http.Error(w, "Failed to decode JSON", http.StatusBadRequest)
-		r.params.Logger.Error("Failed to decode JSON", zap.Error(err))
+		http.Error(w, "Failed to decode events", http.StatusBadRequest)
+		r.params.Logger.Error("Failed to decode events", zap.Error(err))
+		return
+	}
+
+	err = r.dispatch(req.Context(), func(ctx context.Context) error {
+		return r.consumeProvenanceEvents(ctx, provenanceEvents)
+	})
+	r.writeResponse(w, err)
+}
+
+// handleProvenanceEventsStream decodes the request incrementally, flushing the events
+// to the pipelines every StreamingFlushSize events to keep memory usage bounded
+func (r *nifiReceiver) handleProvenanceEventsStream(w http.ResponseWriter, req *http.Request) {
+	var consumeErr error
+	batch := make([]translator.ProvenanceEvent, 0, r.config.StreamingFlushSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		events := batch
+		batch = make([]translator.ProvenanceEvent, 0, r.config.StreamingFlushSize)
+		consumeErr = r.dispatch(req.Context(), func(ctx context.Context) error {
+			return r.consumeProvenanceEvents(ctx, events)
+		})
+		return consumeErr
+	}
+
+	err := streamProvenanceEvents(req.Header.Get("Content-Type"), req.Body, func(event translator.ProvenanceEvent) error {
+		batch = append(batch, event)
+		if len(batch) < r.config.StreamingFlushSize {
+			return nil
+		}
+		return flush()
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	if consumeErr != nil {
+		r.writeResponse(w, consumeErr)
 		return
 	}
 
-	traces := r.eventTranslator.TranslateProvenanceEvents(provenanceEvents)
-	spanCount = traces.SpanCount()
-	err = r.nextConsumer.ConsumeTraces(obsCtx, traces)
 	if err != nil {
-		http.Error(w, "Failed to consume traces", http.StatusInternalServerError)
-		r.params.Logger.Error("Failed to consume traces", zap.Error(err))
+		http.Error(w, "Failed to decode events", http.StatusBadRequest)
+		r.params.Logger.Error("Failed to decode events", zap.Error(err))
 		return
 	}
 
-	r.eventTranslator.Cleanup()
-	_, _ = w.Write([]byte("OK"))
+	r.writeResponse(w, nil)
 }
 
-func (r *nifiReceiver) handleBulletinEvents(w http.ResponseWriter, req *http.Request) {
-	obsCtx := r.tReceiver.StartTracesOp(req.Context())
-	var err error
-	var spanCount int
-	var bulletinEvents []translator.BulletinEvent
-	defer func(spanCount *int) {
-		r.tReceiver.EndTracesOp(obsCtx, metadata.Type.String(), *spanCount, err)
-	}(&spanCount)
+// consumeProvenanceEvents sends the provenance events to the configured pipelines, each pipeline
+// drops the events it already received and forgets the ones it failed to consume, so NiFi can
+// redeliver them without the other pipelines consuming them twice
+func (r *nifiReceiver) consumeProvenanceEvents(ctx context.Context, events []translator.ProvenanceEvent) error {
+	if r.nextMetricsConsumer != nil {
+		unique := r.eventTranslator.DeduplicateProvenanceEvents(component.DataTypeMetrics, events)
+		r.telemetry.recordDuplicates(ctx, walKindProvenance, component.DataTypeMetrics, len(events)-len(unique))
+		if len(unique) > 0 {
+			if err := r.consumeProvenanceMetrics(ctx, unique); err != nil {
+				r.eventTranslator.ForgetProvenanceEvents(component.DataTypeMetrics, unique)
+				return fmt.Errorf("failed to consume metrics: %w", err)
+			}
+		}
+	}
 
-	jsonDecoder := json.NewDecoder(req.Body)
-	err = jsonDecoder.Decode(&bulletinEvents)
+	if r.nextTracesConsumer != nil {
+		unique := r.eventTranslator.DeduplicateProvenanceEvents(component.DataTypeTraces, events)
+		r.telemetry.recordDuplicates(ctx, walKindProvenance, component.DataTypeTraces, len(events)-len(unique))
+		if len(unique) > 0 {
+			if err := r.consumeProvenanceTraces(ctx, unique); err != nil {
+				r.eventTranslator.ForgetProvenanceEvents(component.DataTypeTraces, unique)
+				return fmt.Errorf("failed to consume traces: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *nifiReceiver) consumeProvenanceTraces(ctx context.Context, events []translator.ProvenanceEvent) error {
+	obsCtx := r.obsrecv.StartTracesOp(ctx)
+	traces, released := r.eventTranslator.TranslateProvenanceEvents(events)
+	err := r.nextTracesConsumer.ConsumeTraces(obsCtx, traces)
+	r.obsrecv.EndTracesOp(obsCtx, metadata.Type.String(), traces.SpanCount(), err)
 	if err != nil {
-		http.Error(w, "Failed to decode JSON", http.StatusBadRequest)
-		r.params.Logger.Error("Failed to decode JSON", zap.Error(err))
+		// the held bulletins are released again when the events are redelivered
+		r.eventTranslator.HoldBulletins(released)
+	}
+	return err
+}
+
+func (r *nifiReceiver) consumeProvenanceMetrics(ctx context.Context, events []translator.ProvenanceEvent) error {
+	obsCtx := r.obsrecv.StartMetricsOp(ctx)
+	metrics := r.eventTranslator.TranslateProvenanceEventsToMetrics(events)
+	err := r.nextMetricsConsumer.ConsumeMetrics(obsCtx, metrics)
+	r.obsrecv.EndMetricsOp(obsCtx, metadata.Type.String(), metrics.DataPointCount(), err)
+	return err
+}
+
+// receiveSiteToSite decodes the flowfiles of a Site-to-Site transaction, each flowfile
+// holds a batch of events as sent by the reporting tasks, encoded according to its mime.type
+func (r *nifiReceiver) receiveSiteToSite(ctx context.Context, port sitetosite.Port, packets []sitetosite.DataPacket) error {
+	if r.wal != nil {
+		return r.receiveSiteToSiteDurable(ctx, port, packets)
+	}
+
+	switch port.Name {
+	case r.config.SiteToSite.ProvenancePortName:
+		var events []translator.ProvenanceEvent
+		for _, packet := range packets {
+			batch, err := decodeProvenanceEvents(packet.Attributes["mime.type"], bytes.NewReader(packet.Content))
+			if err != nil {
+				r.params.Logger.Error("Failed to decode events", zap.String("port", port.Name), zap.Error(err))
+				r.refusePacket(ctx, walKindProvenance, err)
+				continue
+			}
+			events = append(events, batch...)
+		}
+		return r.dispatch(ctx, func(ctx context.Context) error {
+			return r.consumeProvenanceEvents(ctx, events)
+		})
+	case r.config.SiteToSite.BulletinPortName:
+		var events []translator.BulletinEvent
+		for _, packet := range packets {
+			batch, err := decodeBulletinEvents(packet.Attributes["mime.type"], bytes.NewReader(packet.Content))
+			if err != nil {
+				r.params.Logger.Error("Failed to decode events", zap.String("port", port.Name), zap.Error(err))
+				r.refusePacket(ctx, walKindBulletins, err)
+				continue
+			}
+			events = append(events, batch...)
+		}
+		return r.dispatch(ctx, func(ctx context.Context) error {
+			return r.consumeBulletinEvents(ctx, events)
+		})
+	default:
+		return fmt.Errorf("unknown site-to-site port %q", port.Name)
+	}
+}
+
+// receiveSiteToSiteDurable persists each flowfile of a Site-to-Site transaction to the write-ahead log
+func (r *nifiReceiver) receiveSiteToSiteDurable(ctx context.Context, port sitetosite.Port, packets []sitetosite.DataPacket) error {
+	kind := walKindProvenance
+	switch port.Name {
+	case r.config.SiteToSite.ProvenancePortName:
+	case r.config.SiteToSite.BulletinPortName:
+		kind = walKindBulletins
+	default:
+		return fmt.Errorf("unknown site-to-site port %q", port.Name)
+	}
+
+	for _, packet := range packets {
+		err := r.acceptRecord(ctx, wal.Record{
+			Kind:        kind,
+			ContentType: packet.Attributes["mime.type"],
+			Body:        packet.Content,
+			ReceivedAt:  time.Now(),
+		})
+		if errors.Is(err, errInvalidPayload) {
+			r.params.Logger.Error("Failed to decode events", zap.String("port", port.Name), zap.Error(err))
+			r.refusePacket(ctx, kind, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refusePacket records a Site-to-Site flowfile that couldn't be decoded as refused by the
+// pipelines of its kind. The transaction is still confirmed, NiFi would otherwise send the
+// flowfile again forever, and since its events are unknown it counts as a single item
+func (r *nifiReceiver) refusePacket(ctx context.Context, kind string, err error) {
+	if r.nextTracesConsumer != nil {
+		r.obsrecv.EndTracesOp(r.obsrecv.StartTracesOp(ctx), metadata.Type.String(), 1, err)
+	}
+	if kind == walKindProvenance && r.nextMetricsConsumer != nil {
+		r.obsrecv.EndMetricsOp(r.obsrecv.StartMetricsOp(ctx), metadata.Type.String(), 1, err)
+	}
+	if kind == walKindBulletins && r.nextLogsConsumer != nil {
+		r.obsrecv.EndLogsOp(r.obsrecv.StartLogsOp(ctx), metadata.Type.String(), 1, err)
+	}
+}
+
+func (r *nifiReceiver) handleBulletinEvents(w http.ResponseWriter, req *http.Request) {
+	if r.wal != nil {
+		r.handleDurable(w, req, walKindBulletins)
 		return
 	}
 
-	traces := r.eventTranslator.TranslateBulletinEvents(bulletinEvents)
-	spanCount = traces.SpanCount()
-	err = r.nextConsumer.ConsumeTraces(obsCtx, traces)
+	bulletinEvents, err := decodeBulletinEvents(req.Header.Get("Content-Type"), req.Body)
 	if err != nil {
-		http.Error(w, "Failed to consume traces", http.StatusInternalServerError)
-		r.params.Logger.Error("Failed to consume traces", zap.Error(err))
+		http.Error(w, "Failed to decode events", http.StatusBadRequest)
+		r.params.Logger.Error("Failed to decode events", zap.Error(err))
 		return
 	}
 
-	r.eventTranslator.Cleanup()
-	_, _ = w.Write([]byte("OK"))
+	err = r.dispatch(req.Context(), func(ctx context.Context) error {
+		return r.consumeBulletinEvents(ctx, bulletinEvents)
+	})
+	r.writeResponse(w, err)
+}
+
+// consumeBulletinEvents sends the bulletin events to the configured pipelines, each pipeline
+// drops the bulletins it already received and forgets the ones it failed to consume, so NiFi
+// can redeliver them without the other pipelines consuming them twice
+func (r *nifiReceiver) consumeBulletinEvents(ctx context.Context, events []translator.BulletinEvent) error {
+	if r.nextLogsConsumer != nil {
+		unique := r.eventTranslator.DeduplicateBulletinEvents(component.DataTypeLogs, events)
+		r.telemetry.recordDuplicates(ctx, walKindBulletins, component.DataTypeLogs, len(events)-len(unique))
+		if len(unique) > 0 {
+			if err := r.consumeBulletinLogs(ctx, unique); err != nil {
+				r.eventTranslator.ForgetBulletinEvents(component.DataTypeLogs, unique)
+				return fmt.Errorf("failed to consume logs: %w", err)
+			}
+		}
+	}
+
+	if r.nextTracesConsumer != nil {
+		unique := r.eventTranslator.DeduplicateBulletinEvents(component.DataTypeTraces, events)
+		r.telemetry.recordDuplicates(ctx, walKindBulletins, component.DataTypeTraces, len(events)-len(unique))
+		if len(unique) > 0 {
+			if err := r.consumeBulletinTraces(ctx, unique); err != nil {
+				r.eventTranslator.ForgetBulletinEvents(component.DataTypeTraces, unique)
+				return fmt.Errorf("failed to consume traces: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *nifiReceiver) consumeBulletinTraces(ctx context.Context, events []translator.BulletinEvent) error {
+	obsCtx := r.obsrecv.StartTracesOp(ctx)
+	traces := r.eventTranslator.TranslateBulletinEvents(events)
+	err := r.nextTracesConsumer.ConsumeTraces(obsCtx, traces)
+	r.obsrecv.EndTracesOp(obsCtx, metadata.Type.String(), traces.SpanCount(), err)
+	return err
+}
+
+// releaseHeldBulletins sends the bulletins held past their timeout at now to the traces pipeline
+func (r *nifiReceiver) releaseHeldBulletins(ctx context.Context, now time.Time) {
+	if r.nextTracesConsumer == nil {
+		return
+	}
+
+	traces, released := r.eventTranslator.ReleaseExpiredBulletins(now)
+	if traces.SpanCount() == 0 {
+		return
+	}
+
+	obsCtx := r.obsrecv.StartTracesOp(ctx)
+	err := r.nextTracesConsumer.ConsumeTraces(obsCtx, traces)
+	r.obsrecv.EndTracesOp(obsCtx, metadata.Type.String(), traces.SpanCount(), err)
+	if err != nil {
+		r.params.Logger.Warn("failed to consume held bulletins, returning them to the hold", zap.Error(err))
+		r.eventTranslator.HoldBulletins(released)
+	}
+}
+
+func (r *nifiReceiver) consumeBulletinLogs(ctx context.Context, events []translator.BulletinEvent) error {
+	obsCtx := r.obsrecv.StartLogsOp(ctx)
+	logs := r.eventTranslator.TranslateBulletinEventsToLogs(events)
+	err := r.nextLogsConsumer.ConsumeLogs(obsCtx, logs)
+	r.obsrecv.EndLogsOp(obsCtx, metadata.Type.String(), logs.LogRecordCount(), err)
+	return err
 }
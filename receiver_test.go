@@ -0,0 +1,471 @@
+package nifireceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.uber.org/zap"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/metadata"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/sitetosite"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/wal"
+)
+
+func newTestProvenanceBody(t *testing.T, count int) string {
+	events := make([]translator.ProvenanceEvent, 0, count)
+	for i := 0; i < count; i++ {
+		events = append(events, translator.ProvenanceEvent{
+			EventId:          uuid.NewString(),
+			EventOrdinal:     int64(i),
+			EventType:        translator.ProvenanceEventTypeCreate,
+			TimestampMillis:  1700000000000,
+			EntityId:         uuid.NewString(),
+			ProcessGroupName: "ingest",
+		})
+	}
+
+	data, err := json.Marshal(events)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestHandleProvenanceEventsStream(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.StreamingFlushSize = 2
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 5)))
+	r.handleProvenanceEvents(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 5, sink.SpanCount())
+	assert.Len(t, sink.AllTraces(), 3, "events should be flushed every 2 spans")
+}
+
+func TestHandleProvenanceEventsStreamInvalid(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.StreamingFlushSize = 2
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+	require.NoError(t, r.registerTracesConsumer(consumertest.NewNop()))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(`{"eventId": "not an array"}`))
+	r.handleProvenanceEvents(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+type fakeMemoryLimiter struct {
+	refuse bool
+}
+
+func (f *fakeMemoryLimiter) MustRefuse() bool {
+	return f.refuse
+}
+
+func TestHandleProvenanceEventsQueue(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Queue.Enabled = true
+	cfg.Queue.Size = 1
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	// no workers are started so the queue fills up after the first request
+	r.queue = newIngestQueue(r.params.Logger, cfg.Queue.Size)
+	handler := r.withBackpressure(http.HandlerFunc(r.handleProvenanceEvents))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 1))))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 1))))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("Retry-After"))
+
+	r.queue.start(1)
+	require.NoError(t, r.queue.shutdown(context.Background()))
+	assert.Equal(t, 1, sink.SpanCount(), "queued events should be drained on shutdown")
+}
+
+func TestHandleProvenanceEventsMemoryLimiter(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	limiter := &fakeMemoryLimiter{refuse: true}
+	r.memoryLimiter = limiter
+	handler := r.withBackpressure(http.HandlerFunc(r.handleProvenanceEvents))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 1))))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Zero(t, sink.SpanCount())
+
+	limiter.refuse = false
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 1))))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 1, sink.SpanCount())
+}
+
+func TestHandleProvenanceEventsWAL(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.WAL.Enabled = true
+	cfg.WAL.Directory = t.TempDir()
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+	require.NoError(t, r.registerTracesConsumer(consumertest.NewErr(consumererror.NewPermanent(errors.New("rejected")))))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		r.backgroundWG.Wait()
+	}()
+	require.NoError(t, r.startWAL(ctx, componenttest.NewNopHost()))
+
+	rec := httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 1))))
+	assert.Equal(t, http.StatusOK, rec.Code, "dead-lettered batches are acknowledged")
+
+	deadLetters, err := r.wal.DeadLetters(context.Background())
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Contains(t, deadLetters[0].Record.Reason, "rejected")
+
+	// transient failures are left to NiFi to retry
+	require.NoError(t, r.registerTracesConsumer(consumertest.NewErr(errors.New("unavailable"))))
+	rec = httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 1))))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	pending, err := r.wal.Pending(context.Background(), 0, walPageSize)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestReplayWAL(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.WAL.Enabled = true
+	cfg.WAL.Directory = t.TempDir()
+
+	// batches left pending by a previous run
+	log, err := wal.NewDirLog(zap.NewNop(), cfg.WAL.Directory)
+	require.NoError(t, err)
+	_, err = log.Append(context.Background(), wal.Record{Kind: walKindProvenance, Body: []byte(newTestProvenanceBody(t, 3))})
+	require.NoError(t, err)
+	_, err = log.Append(context.Background(), wal.Record{Kind: walKindProvenance, Body: []byte("not json")})
+	require.NoError(t, err)
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, r.startWAL(ctx, componenttest.NewNopHost()))
+	assert.Eventually(t, func() bool {
+		pending, err := log.Pending(context.Background(), 0, walPageSize)
+		return err == nil && len(pending) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	r.backgroundWG.Wait()
+
+	assert.Equal(t, 3, sink.SpanCount())
+
+	deadLetters, err := log.DeadLetters(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, deadLetters, 1, "undecodable batches should be dead-lettered")
+}
+
+func TestRetryWAL(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.WAL.Enabled = true
+	cfg.WAL.Directory = t.TempDir()
+	cfg.WAL.RetryInterval = 10 * time.Millisecond
+
+	log, err := wal.NewDirLog(zap.NewNop(), cfg.WAL.Directory)
+	require.NoError(t, err)
+	_, err = log.Append(context.Background(), wal.Record{Kind: walKindProvenance, Body: []byte(newTestProvenanceBody(t, 2))})
+	require.NoError(t, err)
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	// the replay on start fails with a transient error, the batch is delivered by a later retry
+	sink := new(consumertest.TracesSink)
+	var calls atomic.Int32
+	next, err := consumer.NewTraces(func(ctx context.Context, td ptrace.Traces) error {
+		if calls.Add(1) == 1 {
+			return errors.New("unavailable")
+		}
+		return sink.ConsumeTraces(ctx, td)
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.registerTracesConsumer(next))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, r.startWAL(ctx, componenttest.NewNopHost()))
+	assert.Eventually(t, func() bool { return sink.SpanCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	r.backgroundWG.Wait()
+
+	pending, err := log.Pending(context.Background(), 0, walPageSize)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	assert.Equal(t, 2, sink.SpanCount(), "retried batches should be consumed once")
+}
+
+func TestHandleProvenanceEventsDuplicates(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+	require.NoError(t, r.registerTracesConsumer(consumertest.NewErr(errors.New("unavailable"))))
+
+	body := newTestProvenanceBody(t, 2)
+	rec := httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(body)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	// events that failed are accepted when NiFi retries, and dropped once consumed
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+	for i := 0; i < 2; i++ {
+		rec = httptest.NewRecorder()
+		r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, 2, sink.SpanCount())
+}
+
+func TestHandleProvenanceEventsDuplicatesPerSignal(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	metrics := new(consumertest.MetricsSink)
+	require.NoError(t, r.registerMetricsConsumer(metrics))
+	require.NoError(t, r.registerTracesConsumer(consumertest.NewErr(errors.New("unavailable"))))
+
+	body := newTestProvenanceBody(t, 2)
+	rec := httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(body)))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Len(t, metrics.AllMetrics(), 1)
+
+	// the redelivered batch is only consumed by the pipeline that failed
+	traces := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(traces))
+	rec = httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 2, traces.SpanCount())
+	assert.Len(t, metrics.AllMetrics(), 1, "metrics shouldn't be emitted twice")
+}
+
+func TestSpanContextJanitor(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.SpanContextStore.TTL = time.Millisecond
+	cfg.SpanContextStore.ExpiryInterval = 10 * time.Millisecond
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+	require.NoError(t, r.registerTracesConsumer(consumertest.NewNop()))
+
+	rec := httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(newTestProvenanceBody(t, 3))))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	store := r.translatorSettings.SpanContextStore
+	require.Equal(t, 3, store.Len())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, r.startJanitor(ctx))
+	assert.Eventually(t, func() bool { return store.Len() == 0 }, time.Second, 10*time.Millisecond,
+		"expired span contexts should be removed without further requests")
+
+	cancel()
+	r.backgroundWG.Wait()
+}
+
+func TestShutdownReleasesHeldBulletins(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Queue.Enabled = true
+	cfg.BulletinHold.Enabled = true
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	// the bulletin is drained from the queue on shutdown, then held for its unknown flowfile
+	r.queue = newIngestQueue(r.params.Logger, cfg.Queue.Size)
+	body := `[{"objectId":"7a0f1c2e-0000-4000-8000-000000000001","bulletinId":1,"bulletinLevel":"ERROR",` +
+		`"bulletinTimestamp":"2024-04-05T10:20:00.700Z","bulletinFlowFileUuid":"d4c3b2a1-0f9e-4d8c-b7a6-958473625140"}]`
+	rec := httptest.NewRecorder()
+	r.handleBulletinEvents(rec, httptest.NewRequest(http.MethodPost, cfg.BulletinURLPath, strings.NewReader(body)))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	r.queue.start(1)
+	require.NoError(t, r.Shutdown(context.Background()))
+	assert.Equal(t, 1, sink.SpanCount(), "held bulletins should be released on shutdown")
+}
+
+func TestHeldBulletinsConsumeFailures(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.BulletinHold.Enabled = true
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	var failing atomic.Bool
+	next, err := consumer.NewTraces(func(ctx context.Context, td ptrace.Traces) error {
+		if failing.Load() {
+			return errors.New("unavailable")
+		}
+		return sink.ConsumeTraces(ctx, td)
+	})
+	require.NoError(t, err)
+	require.NoError(t, r.registerTracesConsumer(next))
+
+	componentID, flowFileID := uuid.NewString(), uuid.NewString()
+	bulletin := func(id int, flowFileID string) string {
+		return fmt.Sprintf(`[{"objectId":"7a0f1c2e-0000-4000-8000-00000000000%d","bulletinId":%d,"bulletinLevel":"ERROR",`+
+			`"bulletinTimestamp":"2024-04-05T10:20:00.700Z","bulletinSourceId":%q,"bulletinFlowFileUuid":%q}]`, id, id, componentID, flowFileID)
+	}
+	provenance, err := json.Marshal([]translator.ProvenanceEvent{{
+		EventId:         uuid.NewString(),
+		EventType:       translator.ProvenanceEventTypeCreate,
+		TimestampMillis: 1700000000000,
+		EntityId:        flowFileID,
+		ComponentId:     componentID,
+	}})
+	require.NoError(t, err)
+
+	post := func(handler http.HandlerFunc, path, body string) int {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	// the held bulletin stays seen when its batch fails, so the redelivered batch doesn't hold it twice
+	failing.Store(true)
+	assert.Equal(t, http.StatusInternalServerError, post(r.handleBulletinEvents, cfg.BulletinURLPath, bulletin(1, flowFileID)))
+	failing.Store(false)
+	assert.Equal(t, http.StatusOK, post(r.handleBulletinEvents, cfg.BulletinURLPath, bulletin(1, flowFileID)))
+
+	// the bulletin released by a failed provenance batch is returned to the hold
+	failing.Store(true)
+	assert.Equal(t, http.StatusInternalServerError, post(r.handleProvenanceEvents, cfg.ProvenanceURLPath, string(provenance)))
+	failing.Store(false)
+	assert.Equal(t, http.StatusOK, post(r.handleProvenanceEvents, cfg.ProvenanceURLPath, string(provenance)))
+	require.Equal(t, 1, sink.SpanCount())
+	span := sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	assert.Equal(t, 1, span.Events().Len(), "the bulletin should be recorded once on the provenance span")
+
+	// expired bulletins failing to be consumed are returned to the hold as well
+	assert.Equal(t, http.StatusOK, post(r.handleBulletinEvents, cfg.BulletinURLPath, bulletin(2, uuid.NewString())))
+	failing.Store(true)
+	r.releaseHeldBulletins(context.Background(), time.Now().Add(2*cfg.BulletinHold.Timeout))
+	failing.Store(false)
+	r.releaseHeldBulletins(context.Background(), time.Now().Add(2*cfg.BulletinHold.Timeout))
+	assert.Equal(t, 2, sink.SpanCount())
+}
+
+func TestHandleProvenanceEventsRecordChanges(t *testing.T) {
+	cfg := createDefaultConfig().(*Config)
+	cfg.Attributes.RecordChanges = true
+
+	r, err := newNifiReceiver(cfg, receivertest.NewNopCreateSettings())
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	body, err := json.Marshal([]translator.ProvenanceEvent{{
+		EventId:            uuid.NewString(),
+		EventType:          translator.ProvenanceEventTypeAttributesModified,
+		TimestampMillis:    1700000000000,
+		EntityId:           uuid.NewString(),
+		PreviousAttributes: map[string]string{"filename": "readings.json", "mime.type": "text/plain"},
+		UpdatedAttributes:  map[string]string{"filename": "readings.json", "mime.type": "application/json"},
+	}})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	r.handleProvenanceEvents(rec, httptest.NewRequest(http.MethodPost, cfg.ProvenanceURLPath, strings.NewReader(string(body))))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, sink.SpanCount())
+
+	// the span only carries the diff event, not the full attribute set
+	span := sink.AllTraces()[0].ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0)
+	for key := range span.Attributes().AsRaw() {
+		assert.NotContains(t, key, "nifi.attributes.")
+	}
+	require.Equal(t, 1, span.Events().Len())
+	assert.Equal(t, "attributes.changed", span.Events().At(0).Name())
+}
+
+func TestReceiveSiteToSiteRefusesUndecodablePackets(t *testing.T) {
+	tt, err := componenttest.SetupTelemetry(component.NewID(metadata.Type))
+	require.NoError(t, err)
+	defer func() { require.NoError(t, tt.Shutdown(context.Background())) }()
+
+	settings := receivertest.NewNopCreateSettings()
+	settings.ID = component.NewID(metadata.Type)
+	settings.TelemetrySettings = tt.TelemetrySettings()
+
+	cfg := createDefaultConfig().(*Config)
+	r, err := newNifiReceiver(cfg, settings)
+	require.NoError(t, err)
+
+	sink := new(consumertest.TracesSink)
+	require.NoError(t, r.registerTracesConsumer(sink))
+
+	// the transaction is confirmed with the decoded events, the other flowfile is refused
+	port := sitetosite.Port{Name: cfg.SiteToSite.ProvenancePortName}
+	require.NoError(t, r.receiveSiteToSite(context.Background(), port, []sitetosite.DataPacket{
+		{Attributes: map[string]string{"mime.type": "application/json"}, Content: []byte(newTestProvenanceBody(t, 1))},
+		{Attributes: map[string]string{"mime.type": "application/json"}, Content: []byte("not json")},
+	}))
+
+	assert.Equal(t, 1, sink.SpanCount())
+	require.NoError(t, tt.CheckReceiverTraces("http", 1, 1))
+}
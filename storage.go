@@ -0,0 +1,33 @@
+package nifireceiver
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension/experimental/storage"
+)
+
+// getStorageClient returns a client of the configured storage extension,
+// or a no-op client when no storage extension is configured
+func getStorageClient(ctx context.Context, host component.Host, storageID *component.ID, id component.ID, name string) (storage.Client, error) {
+	if storageID == nil {
+		return storage.NewNopClient(), nil
+	}
+
+	ext, ok := host.GetExtensions()[*storageID]
+	if !ok {
+		return nil, fmt.Errorf("storage extension %q not found", storageID)
+	}
+
+	storageExt, ok := ext.(storage.Extension)
+	if !ok {
+		return nil, fmt.Errorf("extension %q is not a storage extension", storageID)
+	}
+
+	client, err := storageExt.GetClient(ctx, component.KindReceiver, id, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage client: %w", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,71 @@
+package nifireceiver
+
+import (
+	"context"
+
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/metadata"
+	"github.com/tvaintrob/otel-collector-nifi-receiver/internal/translator"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// receiverTelemetry records the receiver's own metrics
+type receiverTelemetry struct {
+	meter           metric.Meter
+	duplicateEvents metric.Int64Counter
+}
+
+func newReceiverTelemetry(settings component.TelemetrySettings) (*receiverTelemetry, error) {
+	meter := metadata.Meter(settings)
+	duplicateEvents, err := meter.Int64Counter("nifi_receiver_duplicate_events",
+		metric.WithDescription("Number of events dropped because they were already received"),
+		metric.WithUnit("{events}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &receiverTelemetry{meter: meter, duplicateEvents: duplicateEvents}, nil
+}
+
+// recordDuplicates records the number of duplicate events dropped by the signal's pipeline,
+// kind is either provenance or bulletins
+func (t *receiverTelemetry) recordDuplicates(ctx context.Context, kind string, signal component.DataType, count int) {
+	if count > 0 {
+		t.duplicateEvents.Add(ctx, int64(count), metric.WithAttributes(
+			attribute.String("kind", kind), attribute.String("signal", signal.String())))
+	}
+}
+
+// observeStore reports the size and counters of the span context store until unregistered
+func (t *receiverTelemetry) observeStore(store translator.SpanContextStore) (metric.Registration, error) {
+	size, err := t.meter.Int64ObservableGauge("nifi_receiver_span_context_store_size",
+		metric.WithDescription("Number of flowfiles tracked by the span context store"),
+		metric.WithUnit("{flowfiles}"))
+	if err != nil {
+		return nil, err
+	}
+
+	evictions, err := t.meter.Int64ObservableCounter("nifi_receiver_span_context_store_evictions",
+		metric.WithDescription("Number of span contexts removed from the store, either to make room or once expired"),
+		metric.WithUnit("{flowfiles}"))
+	if err != nil {
+		return nil, err
+	}
+
+	expiredLookups, err := t.meter.Int64ObservableCounter("nifi_receiver_span_context_store_expired_lookups",
+		metric.WithDescription("Number of lookups of a flowfile whose span context had already expired"),
+		metric.WithUnit("{lookups}"))
+	if err != nil {
+		return nil, err
+	}
+
+	return t.meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		stats := store.Stats()
+		observer.ObserveInt64(size, int64(store.Len()))
+		observer.ObserveInt64(evictions, stats.Evictions, metric.WithAttributes(attribute.String("reason", "capacity")))
+		observer.ObserveInt64(evictions, stats.Expirations, metric.WithAttributes(attribute.String("reason", "expired")))
+		observer.ObserveInt64(expiredLookups, stats.ExpiredLookups)
+		return nil
+	}, size, evictions, expiredLookups)
+}